@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+func addCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Add a planet from a file, stdin or the clipboard",
+		ArgsUsage: "[file]",
+		Description: "Text input may be base64, hex or a C array (as printed by mkworld), the encoding is\n" +
+			"detected automatically. Files may also contain the raw planet.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "stdin", Usage: "Read the planet as text from stdin"},
+			&cli.BoolFlag{Name: "clipboard", Usage: "Read the planet as text from the clipboard (falls back to an OSC52 query)"},
+			&cli.StringFlag{Name: "remark", Usage: "Remark of the new entry"},
+		},
+		Action: addAction,
+	}
+}
+
+func addAction(c *cli.Context) error {
+	remark := c.String("remark")
+	var world *tools.World
+	var format string
+	switch {
+	case c.Bool("stdin"):
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		world, format, err = tools.DecodeWorldText(string(text))
+		if err != nil {
+			return err
+		}
+	case c.Bool("clipboard"):
+		text, err := tools.ReadClipboard()
+		if err != nil {
+			return err
+		}
+		world, format, err = tools.DecodeWorldText(text)
+		if err != nil {
+			return err
+		}
+	case c.NArg() == 1:
+		data, err := os.ReadFile(c.Args().First())
+		if err != nil {
+			return err
+		}
+		format = "binary"
+		if world, err = tools.ParseWorld(data); err != nil {
+			var textErr error
+			if world, format, textErr = tools.DecodeWorldText(string(data)); textErr != nil {
+				return err
+			}
+		}
+		if remark == "" {
+			remark = filepath.Base(c.Args().First())
+		}
+	default:
+		return fmt.Errorf("usage: add [--remark <remark>] --stdin | --clipboard | <file>")
+	}
+
+	var planet *configs.ZerotierPlanetFile
+	var isRevision bool
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		var err error
+		planet, isRevision, err = tools.StoreWorld(cfg, world, remark)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if isRevision {
+		fmt.Printf("Added (%s) as a revision of %s\n", format, planet.Remark)
+	} else {
+		fmt.Printf("Added (%s) as %s\n", format, planet.Remark)
+	}
+	for _, issue := range tools.LintWorld(world, false) {
+		fmt.Printf("  %s\n", issue)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func backupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "backup",
+		Usage: "Manage automatic profile backups",
+		Description: "The profile is backed up to the backups directory next to it before every change,\n" +
+			"keeping the most recent ones (see \"backup retention\").",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List backups and what they contain, newest first",
+				Action: backupListAction,
+			},
+			{
+				Name:      "restore",
+				Usage:     "Replace the profile with a backup, the current profile is backed up first",
+				ArgsUsage: "<number|name>",
+				Action:    backupRestoreAction,
+			},
+			{
+				Name:      "retention",
+				Usage:     "Show or set the number of backups kept, -1 disables automatic backups",
+				ArgsUsage: "[count]",
+				Action:    backupRetentionAction,
+			},
+		},
+	}
+}
+
+func backupListAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	backups, err := cfg.ListBackups(true)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Printf("no backups in %s\n", cfg.GetBackupDir())
+		return nil
+	}
+	for i, backup := range backups {
+		fmt.Printf("%3d  %s  %s\n     %s\n", i+1, backup.Time.Format(time.DateTime), backup.Name, backup.Describe())
+	}
+	return nil
+}
+
+func backupRestoreAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: backup restore <number|name>")
+	}
+	var backup *configs.ProfileBackup
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		var err error
+		if backup, err = findBackup(cfg, c.Args().First()); err != nil {
+			return err
+		}
+		return cfg.LoadBackup(*backup)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %s (%s)\n", backup.Name, backup.Time.Format(time.DateTime))
+	return nil
+}
+
+func backupRetentionAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		cfg, err := loadProfile(c)
+		if err != nil {
+			return err
+		}
+		fmt.Println(cfg.BackupRetention())
+		return nil
+	}
+	count, err := strconv.Atoi(c.Args().First())
+	if err != nil || count == 0 || count < -1 {
+		return fmt.Errorf("retention must be a positive number, or -1 to disable backups")
+	}
+	cfg, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		cfg.Backups = &count
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if source := cfg.EffectiveSetting("backups"); source.Source == configs.SourceEnv {
+		fmt.Printf("saved, but %s overrides it (%s backups are kept)\n", source.Origin, source.Value)
+	}
+	return nil
+}
+
+// findBackup 按序号(1为最新)或文件名查找备份
+func findBackup(cfg *configs.ZerotierSwitcherProfile, ref string) (*configs.ProfileBackup, error) {
+	backups, err := cfg.ListBackups(false)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(backups) {
+			return nil, fmt.Errorf("backup %d not found, %d backups available", n, len(backups))
+		}
+		return &backups[n-1], nil
+	}
+	for i := range backups {
+		if backups[i].Name == ref {
+			return &backups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("backup (%s) not found", ref)
+}
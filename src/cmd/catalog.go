@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func catalogCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "catalog",
+		Usage: "Manage subscribed planet catalogs",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Subscribe to a catalog",
+				ArgsUsage: "<name> <url|directory>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "key", Aliases: []string{"k"}, Usage: "Team public key (hex)", Required: true},
+					&cli.BoolFlag{Name: "no-sync", Usage: "Do not sync after subscribing"},
+				},
+				Action: catalogAddAction,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Unsubscribe from a catalog and drop its planets",
+				ArgsUsage: "<name>",
+				Action:    catalogRemoveAction,
+			},
+			{
+				Name:   "list",
+				Usage:  "List subscribed catalogs",
+				Action: catalogListAction,
+			},
+			{
+				Name:      "sync",
+				Usage:     "Sync catalogs into the profile",
+				ArgsUsage: "[name...]",
+				Action:    catalogSyncAction,
+			},
+			{
+				Name:  "keygen",
+				Usage: "Generate a team key for signing catalogs",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "Private key output file", Required: true},
+				},
+				Action: catalogKeygenAction,
+			},
+		},
+	}
+}
+
+func catalogAddAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("usage: catalog add --key <hex> <name> <url|directory>")
+	}
+	name, source := c.Args().Get(0), c.Args().Get(1)
+	if _, err := tools.ParseCatalogPublicKey(c.String("key")); err != nil {
+		return err
+	}
+	catalog := configs.ZerotierCatalog{
+		Name:      name,
+		Source:    source,
+		PublicKey: c.String("key"),
+	}
+	// downloads take longer than the profile lock may be held, so fetch first
+	var snapshot *tools.CatalogSnapshot
+	if !c.Bool("no-sync") {
+		var err error
+		if snapshot, err = tools.FetchCatalog(catalog); err != nil {
+			return err
+		}
+	}
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		if cfg.FindCatalog(name) != nil {
+			return fmt.Errorf("catalog (%s) exists", name)
+		}
+		cfg.Catalogs = append(cfg.Catalogs, catalog)
+		if snapshot != nil {
+			fmt.Printf("%s: %s\n", name, tools.ApplyCatalog(cfg, name, snapshot))
+		}
+		return nil
+	})
+	return err
+}
+
+func catalogRemoveAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: catalog remove <name>")
+	}
+	name := c.Args().First()
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		if cfg.FindCatalog(name) == nil {
+			return fmt.Errorf("catalog (%s) not found", name)
+		}
+		var catalogs []configs.ZerotierCatalog
+		for _, item := range cfg.Catalogs {
+			if item.Name != name {
+				catalogs = append(catalogs, item)
+			}
+		}
+		cfg.Catalogs = catalogs
+
+		// 正在使用的planet转为普通条目，其余的删除
+		cHash := tools.GetCurrentPlanetHashFromOS(cfg)
+		var planets []configs.ZerotierPlanetFile
+		for _, p := range cfg.Planets {
+			if p.Catalog == name {
+				if !tools.CheckIsCurrentPlanet(p.Data, cHash) {
+					continue
+				}
+				p.Catalog, p.CatalogEntry, p.Retired = "", "", false
+			}
+			planets = append(planets, p)
+		}
+		cfg.Planets = planets
+		return nil
+	})
+	return err
+}
+
+func catalogListAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	for _, item := range cfg.Catalogs {
+		count := 0
+		for _, p := range cfg.Planets {
+			if p.Catalog == item.Name {
+				count++
+			}
+		}
+		lastSync := "never"
+		if item.LastSync > 0 {
+			lastSync = time.Unix(item.LastSync, 0).Format(time.RFC3339)
+		}
+		fmt.Printf("%s\n  Source: %s\n  Key: %s\n  Planets: %d\n  Last sync: %s\n", item.Name, item.Source, item.PublicKey, count, lastSync)
+	}
+	return nil
+}
+
+func catalogSyncAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	names := c.Args().Slice()
+	if len(names) == 0 {
+		for _, item := range cfg.Catalogs {
+			names = append(names, item.Name)
+		}
+	}
+
+	// fetch without holding the profile lock, only the results are merged under it
+	var failed error
+	snapshots := map[string]*tools.CatalogSnapshot{}
+	for _, name := range names {
+		catalog := cfg.FindCatalog(name)
+		if catalog == nil {
+			fmt.Printf("%s: catalog not found\n", name)
+			failed = fmt.Errorf("some catalogs failed to sync")
+			continue
+		}
+		snapshot, err := tools.FetchCatalog(*catalog)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			failed = fmt.Errorf("some catalogs failed to sync")
+			continue
+		}
+		snapshots[name] = snapshot
+	}
+	if len(snapshots) == 0 {
+		return failed
+	}
+
+	_, err = updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		for _, name := range names {
+			snapshot, ok := snapshots[name]
+			if !ok {
+				continue
+			}
+			// removed by another process meanwhile
+			if cfg.FindCatalog(name) == nil {
+				fmt.Printf("%s: catalog not found\n", name)
+				failed = fmt.Errorf("some catalogs failed to sync")
+				continue
+			}
+			fmt.Printf("%s: %s\n", name, tools.ApplyCatalog(cfg, name, snapshot))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return failed
+}
+
+func catalogKeygenAction(c *cli.Context) error {
+	pub, priv, err := tools.GenerateCatalogKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.String("out"), []byte(hex.EncodeToString(priv.Seed())+"\n"), 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Private key saved to %s\nPublic key: %s\n", c.String("out"), hex.EncodeToString(pub))
+	return nil
+}
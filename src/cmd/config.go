@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Inspect the profile",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "show",
+				Usage: "Show the settings stored in the profile, or with --effective the values in use and where they come from",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "effective",
+						Usage: "Resolve the system config, the profile and ZTS_* environment variables",
+					},
+				},
+				Action: configShowAction,
+			},
+			{
+				Name:      "validate",
+				Usage:     "Check the profile for unknown fields, invalid entries and settings that do not work on this system without changing it",
+				ArgsUsage: "[profile.json]",
+				Action:    configValidateAction,
+			},
+		},
+	}
+}
+
+func configValidateAction(c *cli.Context) error {
+	profilePath := c.String("config")
+	if c.NArg() > 0 {
+		profilePath = c.Args().First()
+	}
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return err
+	}
+	// the profile is read directly so that it is neither created nor upgraded
+	issues, cfg := configs.ValidateProfileData(data, configs.PlanetStoreOf(profilePath))
+	if cfg != nil {
+		issues = append(issues, validateProfilePlanets(cfg)...)
+	}
+	failed := false
+	for _, issue := range issues {
+		fmt.Println(issue)
+		failed = failed || !issue.Warning
+	}
+	if failed {
+		return cli.Exit("", 1)
+	}
+	fmt.Printf("%s: ok (version %d, %d planets)\n", profilePath, cfg.Version, len(cfg.Planets))
+	return nil
+}
+
+func configShowAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	if !c.Bool("effective") {
+		for _, setting := range configs.ProfileSettings {
+			if value := setting.Get(cfg); value != "" {
+				fmt.Printf("%s = %s\n", setting.Key, value)
+			}
+		}
+		printSettingWarnings(cfg)
+		return nil
+	}
+	profileSource := "default"
+	if env := os.Getenv(configs.EnvConfigPath); env != "" && env == c.String("config") {
+		profileSource = configs.EnvConfigPath
+	} else if c.IsSet("config") {
+		profileSource = "--config"
+	}
+	fmt.Printf("profile: %s (%s)\n", cfg.ConfigPath(), profileSource)
+	systemConfig := configs.GetSystemConfigPath()
+	if _, err := os.Stat(systemConfig); err != nil {
+		systemConfig += " (not found)"
+	}
+	fmt.Printf("system config: %s\n\n", systemConfig)
+	for _, source := range cfg.EffectiveSettings() {
+		fmt.Printf("%-18s %-40s %s\n", source.Setting.Key, source.Value, source)
+	}
+	for _, name := range configs.UnknownEnvVars() {
+		fmt.Printf("\nwarning: unknown environment variable %s\n", name)
+	}
+	printSettingWarnings(cfg)
+	return nil
+}
+
+// printSettingWarnings 输出在当前系统上不可用的配置项，例如目录或命令不存在
+func printSettingWarnings(cfg *configs.ZerotierSwitcherProfile) {
+	for _, issue := range cfg.SettingWarnings() {
+		fmt.Printf("\n%s\n", issue)
+	}
+}
+
+// validateProfilePlanets 检查条目中的planet能否解析，以及与条目记录的信息是否一致
+func validateProfilePlanets(cfg *configs.ZerotierSwitcherProfile) []configs.ProfileIssue {
+	var issues []configs.ProfileIssue
+	for i, p := range cfg.Planets {
+		path := fmt.Sprintf("planets[%d]", i)
+		for _, rev := range p.AllRevisions() {
+			if rev.Data == "" {
+				// reported as missing from the planet store
+				continue
+			}
+			world, err := tools.ParsePlanetBase64(rev.Data)
+			if err != nil {
+				issues = append(issues, configs.ProfileIssue{Path: path, Message: fmt.Sprintf("revision %d: %v", rev.CreateTime, err)})
+				continue
+			}
+			if world.ID != p.WorldId || world.Type != p.WorldType {
+				issues = append(issues, configs.ProfileIssue{Path: path, Message: fmt.Sprintf("revision %d is world %d (type %d), the entry records world %d (type %d)", rev.CreateTime, world.ID, world.Type, p.WorldId, p.WorldType)})
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Compare two planets field by field",
+		ArgsUsage: "<from> <to>",
+		Description: "Each planet can be a file path, \"installed\" for the planet in use, \"original\" for the planet\n" +
+			"preserved on first use, or a stored planet referenced by remark or hash prefix, optionally\n" +
+			"followed by @<timestamp> to select a revision.",
+		Action: diffAction,
+	}
+}
+
+// loadWorldArg 按参数读取planet：文件、已安装或原始的planet，或配置中的planet(版本)
+func loadWorldArg(cfg *configs.ZerotierSwitcherProfile, arg string) (*tools.World, error) {
+	return loadWorldArgWithOptions(cfg, arg, tools.ParseOptions{})
+}
+
+// loadWorldArgWithOptions 按参数读取planet，并按选项解析
+func loadWorldArgWithOptions(cfg *configs.ZerotierSwitcherProfile, arg string, opts tools.ParseOptions) (*tools.World, error) {
+	data, err := loadWorldData(cfg, arg)
+	if err != nil {
+		return nil, err
+	}
+	return tools.ParseWorldWithOptions(data, opts)
+}
+
+// loadWorldData 按参数读取planet的原始数据
+func loadWorldData(cfg *configs.ZerotierSwitcherProfile, arg string) ([]byte, error) {
+	readFile := func(filename string) ([]byte, error) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("read file (%s) error: %v", filename, err)
+		}
+		return data, nil
+	}
+	if arg == "installed" {
+		return readFile(configs.GetPlanetFilePath(cfg))
+	}
+	if arg == "original" {
+		return tools.ReadOriginalPlanet(cfg)
+	}
+	if s, err := os.Stat(arg); err == nil && !s.IsDir() {
+		return readFile(arg)
+	}
+	ref, revision, hasRevision := strings.Cut(arg, "@")
+	planet, err := cfg.FindPlanet(ref)
+	if err != nil {
+		return nil, err
+	}
+	data := planet.ActiveRevision().Data
+	if hasRevision {
+		ts, err := strconv.ParseUint(revision, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid revision timestamp (%s)", revision)
+		}
+		data = ""
+		for _, rev := range planet.AllRevisions() {
+			if rev.CreateTime == ts {
+				data = rev.Data
+				break
+			}
+		}
+		if data == "" {
+			return nil, fmt.Errorf("revision (%s) not found", arg)
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse base64 error: %v", err)
+	}
+	return raw, nil
+}
+
+func diffAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("usage: diff <from> <to>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	from, err := loadWorldArg(cfg, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	to, err := loadWorldArg(cfg, c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	changes := tools.DiffWorlds(from, to)
+	if len(changes) == 0 {
+		fmt.Println("No differences.")
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+	return nil
+}
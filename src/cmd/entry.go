@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/LanceLRQ/zerotier-switcher/src/configs"
 	"github.com/LanceLRQ/zerotier-switcher/src/tools"
@@ -16,15 +16,31 @@ import (
 
 func CommandEntry(version string) {
 	app := &cli.App{
-		Name:     "zerotier-switcher",
-		Usage:    "Zerotier Switcher",
-		Commands: []*cli.Command{},
+		Name:  "zerotier-switcher",
+		Usage: "Zerotier Switcher",
+		Commands: []*cli.Command{
+			addCommand(),
+			catalogCommand(),
+			serveCommand(),
+			diffCommand(),
+			lintCommand(),
+			identityCommand(),
+			inspectCommand(),
+			keyCommand(),
+			moonCommand(),
+			exportCommand(),
+			importCommand(),
+			backupCommand(),
+			resetCommand(),
+			configCommand(),
+		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "config",
 				Aliases:     []string{"c"},
 				Value:       configs.GetDefaultConfigPath(),
 				Usage:       "Config file path",
+				EnvVars:     []string{configs.EnvConfigPath},
 				DefaultText: configs.GetDefaultConfigPath(),
 			},
 		},
@@ -42,34 +58,24 @@ func CommandEntry(version string) {
 			if _, err := os.Stat(planetFilePath); os.IsNotExist(err) {
 				return fmt.Errorf("planet file (%s) not found", planetFilePath)
 			}
+			// 首次使用时原样保存原始的planet文件，以便随时恢复
+			if saved, err := tools.PreserveOriginalPlanet(cfg); err != nil {
+				fmt.Printf("init warning: fail to preserve the original planet file: %v\n", err)
+			} else if saved {
+				fmt.Printf("init: original planet file preserved to %s\n", cfg.GetOriginalPlanetPath())
+			}
 			// 初始化操作
 			if len(cfg.Planets) == 0 {
 				// 如果配置文件里边没有数据，则先自动获取当前的planet信息并写入到文件中
 				world, err := tools.ParsePlanetFile(planetFilePath)
 				if err != nil {
-					fmt.Println("init error: fail to read planet file.")
+					fmt.Printf("init error: fail to read planet file: %v\n", err)
 				} else {
 					fmt.Printf("init: load planet file from %s\n", planetFilePath)
-				}
-				var root tools.Root
-				var ep tools.InetAddress
-				if len(world.Roots) > 0 {
-					root = world.Roots[0]
-					if len(root.StableEndpoints) > 0 {
-						ep = root.StableEndpoints[0]
+					if _, _, err := tools.StoreWorld(cfg, world, "Default"); err == nil {
+						_ = cfg.WriteAppConfig()
 					}
 				}
-				cfg.Planets = append(cfg.Planets, configs.ZerotierPlanetFile{
-					Hash:         hex.EncodeToString(world.Signature[:32]),
-					Remark:       "Default",
-					Data:         world.ToBase64(),
-					CreateTime:   world.Timestamp,
-					WorldId:      world.ID,
-					WorldType:    world.Type,
-					RootIdentity: root.Identity.String(),
-					RootEndpoint: ep.String(),
-				})
-				_ = cfg.WriteAppConfig()
 				fmt.Println("Press any key to continue")
 				_, _ = fmt.Scanf("%s")
 			}
@@ -89,3 +95,34 @@ func CommandEntry(version string) {
 		log.Fatal(err)
 	}
 }
+
+// loadProfile 读取全局参数指定的配置文件，无法读取的planet作为警告输出
+func loadProfile(c *cli.Context) (*configs.ZerotierSwitcherProfile, error) {
+	cfg, err := configs.ReadAppConfig(c.String("config"))
+	if err != nil {
+		return nil, err
+	}
+	printPlanetErrors(cfg)
+	return cfg, nil
+}
+
+// errNoChanges update没有修改配置，不需要写入
+var errNoChanges = errors.New("no changes")
+
+// updateProfile 在持有配置文件锁期间读取、修改并写入全局参数指定的配置文件，update返回错误时不写入
+func updateProfile(c *cli.Context, update func(cfg *configs.ZerotierSwitcherProfile) error) (*configs.ZerotierSwitcherProfile, error) {
+	cfg, err := configs.UpdateProfile(c.String("config"), func(cfg *configs.ZerotierSwitcherProfile) error {
+		printPlanetErrors(cfg)
+		return update(cfg)
+	})
+	if errors.Is(err, errNoChanges) {
+		err = nil
+	}
+	return cfg, err
+}
+
+func printPlanetErrors(cfg *configs.ZerotierSwitcherProfile) {
+	for _, planetErr := range cfg.PlanetErrors() {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", planetErr)
+	}
+}
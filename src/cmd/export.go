@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "Export a single planet",
+		ArgsUsage: "<planet>",
+		Description: "The planet can be a file path, \"installed\" for the planet in use, or a stored planet\n" +
+			"referenced by remark or hash prefix. Formats:\n" +
+			"  binary   the raw planet (or .moon) file\n" +
+			"  base64   base64 text\n" +
+			"  hex      hex text\n" +
+			"  cpp      C++ array as used for the default world in ZeroTier's Topology.cpp\n" +
+			"  json     JSON description of the decoded world\n" +
+			"  mkworld  mkworld JSON config for planets, initmoon definition for moons",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Usage: strings.Join(tools.ExportFormats, ", "), Value: tools.ExportFormatBinary},
+			&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "Output file, - for stdout", Value: "-"},
+		},
+		Action: exportAction,
+	}
+}
+
+func exportAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: export [--format <format>] [-o <file>] <planet>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	world, err := loadWorldArg(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+	data, err := tools.ExportWorld(world, c.String("format"))
+	if err != nil {
+		return err
+	}
+	if c.String("out") == "-" {
+		if c.String("format") == tools.ExportFormatBinary && term.IsTerminal(int(os.Stdout.Fd())) {
+			return fmt.Errorf("refusing to write binary data to the terminal, use -o <file>")
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(c.String("out"), data, 0644)
+}
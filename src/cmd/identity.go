@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func identityCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "identity",
+		Usage: "Work with ZeroTier identities",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "validate",
+				Usage:     "Check that identity addresses are derived from their public keys",
+				ArgsUsage: "<identity.public|identity.secret|identity string...>",
+				Action:    identityValidateAction,
+			},
+		},
+	}
+}
+
+// loadIdentityArg 读取身份文件或身份字符串
+func loadIdentityArg(arg string) (*tools.Identity, []byte, error) {
+	if s, err := os.Stat(arg); err == nil && !s.IsDir() {
+		return tools.ReadIdentityFile(arg)
+	}
+	return tools.ParseIdentityString(arg)
+}
+
+func identityValidateAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("usage: identity validate <identity...>")
+	}
+	failed := false
+	for _, arg := range c.Args().Slice() {
+		identity, _, err := loadIdentityArg(arg)
+		if err == nil {
+			err = identity.Validate()
+		}
+		if errors.Is(err, tools.ErrIdentityValidationUnsupported) {
+			fmt.Printf("%s: %s (%s) %v\n", arg, identity.AddressString(), identity.TypeName(), err)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", arg, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %s valid\n", arg, identity.AddressString())
+	}
+	if failed {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
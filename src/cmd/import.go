@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+
+	"github.com/urfave/cli/v2"
+)
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Merge a profile backup into the profile",
+		ArgsUsage: "<backup.json>",
+		Description: "Planets are deduplicated by content hash. Revisions of worlds already in the profile are\n" +
+			"added to their entries, and entries whose settings (remark, auto join network, networks, tags)\n" +
+			"differ from the backup are reported as conflicts, resolved by --prefer.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "prefer", Usage: "Settings kept on conflicts: local or backup", Value: "local"},
+			&cli.BoolFlag{Name: "dry-run", Aliases: []string{"n"}, Usage: "Only show what would be merged"},
+		},
+		Action: importAction,
+	}
+}
+
+func importAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: import [--prefer local|backup] [--dry-run] <backup.json>")
+	}
+	prefer := c.String("prefer")
+	if prefer != "local" && prefer != "backup" {
+		return fmt.Errorf("--prefer must be local or backup")
+	}
+	merged := false
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		backup, err := configs.ReadProfileFile(c.Args().First(), cfg.PlanetStore())
+		if err != nil {
+			return err
+		}
+
+		plan := tools.PlanMerge(cfg, backup)
+		for _, planet := range plan.Added {
+			fmt.Printf("+ %s (%s)\n", planet.Remark, planet.Hash[:8])
+		}
+		for _, conflict := range plan.Conflicts {
+			conflict.UseBackup = prefer == "backup"
+			fmt.Printf("! %s (%s), keeping the %s settings\n", conflict.Local.Remark, conflict.Local.Hash[:8], prefer)
+			for _, field := range conflict.Fields {
+				fmt.Printf("    %s: %q (local) / %q (backup)\n", field.Name, field.Local, field.Backup)
+			}
+		}
+		for _, catalog := range plan.Catalogs {
+			fmt.Printf("+ catalog %s\n", catalog.Name)
+		}
+		for _, key := range plan.SigningKeys {
+			fmt.Printf("+ signing key %s\n", key.Name)
+		}
+		for _, skipped := range plan.Skipped {
+			fmt.Printf("- skipped %s\n", skipped)
+		}
+		fmt.Println(plan.Summary())
+
+		if c.Bool("dry-run") || plan.IsEmpty() {
+			return errNoChanges
+		}
+		plan.Apply(cfg)
+		merged = true
+		return nil
+	})
+	if err != nil || !merged {
+		return err
+	}
+	fmt.Println("Merged")
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+
+	"github.com/urfave/cli/v2"
+)
+
+func inspectCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "inspect",
+		Usage:     "Show the decoded content of a planet",
+		ArgsUsage: "<planet>",
+		Description: "The planet can be a file path, \"installed\" for the planet in use, or a stored planet\n" +
+			"referenced by remark or hash prefix. Damaged files are decoded as far as possible.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "hex", Usage: "Show the raw bytes with every field labelled by offset"},
+		},
+		Action: inspectAction,
+	}
+}
+
+func inspectAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: inspect [--hex] <planet>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	world, err := loadWorldArgWithOptions(cfg, c.Args().First(), tools.ParseOptions{Lenient: true})
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("hex") {
+		fmt.Printf("%-8s  %-*s  %s\n", "offset", tools.HexBytesPerRow*3-1, "bytes", "field")
+		for _, row := range tools.HexLayout(world) {
+			fmt.Println(row.String())
+		}
+	} else {
+		fmt.Printf("ZeroTier Planet Information:\n")
+		fmt.Printf("  ID: %d\n", world.ID)
+		fmt.Printf("  Type: %d (1=Planet, 127=Moon)\n", world.Type)
+		fmt.Printf("  Timestamp: %d\n", world.Timestamp)
+		fmt.Printf("  Update Signer Public Key: %s\n", hex.EncodeToString(world.UpdatesMustBeSignedBy[:]))
+		fmt.Printf("  Signature: %s...\n", hex.EncodeToString(world.Signature[:16]))
+		fmt.Printf("  Number of Roots: %d\n", len(world.Roots))
+
+		for i, root := range world.Roots {
+			fmt.Printf("\nRoot Server %d:\n", i+1)
+			fmt.Printf("  Identity: %s\n", root.Identity.String())
+			for j, ep := range root.StableEndpoints {
+				fmt.Printf("  Endpoint %d: %s\n", j+1, ep.String())
+			}
+		}
+		if len(world.Problems) > 0 {
+			fmt.Printf("\nProblems:\n")
+			for _, problem := range world.Problems {
+				fmt.Printf("  %v\n", problem)
+			}
+		}
+	}
+
+	if len(world.Problems) > 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
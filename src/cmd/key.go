@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+func keyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "key",
+		Usage: "Manage C25519 keys used to sign planet updates",
+		Description: "Keys are stored in the profile encrypted with a passphrase. The passphrase is read from\n" +
+			"the terminal, or from the first line of stdin when it is not a terminal.",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "generate",
+				Usage:     "Generate a new signing key",
+				ArgsUsage: "<name>",
+				Action:    keyGenerateAction,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import a key pair written by mkworld (current.c25519 / previous.c25519)",
+				ArgsUsage: "<name> <file>",
+				Action:    keyImportAction,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export a key pair in the mkworld format",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "Output file", Required: true},
+				},
+				Action: keyExportAction,
+			},
+			{
+				Name:   "list",
+				Usage:  "List signing keys and the planets they can sign updates for",
+				Action: keyListAction,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Remove a signing key",
+				ArgsUsage: "<name>",
+				Action:    keyRemoveAction,
+			},
+		},
+	}
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readPassphrase 读取口令，confirm为true时要求输入两次
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read passphrase error: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(passphrase) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
+// storeSigningKey 加密并保存密钥对
+func storeSigningKey(c *cli.Context, name string, pair *tools.C25519KeyPair) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	// checked before asking for the passphrase, and again when saving
+	if err := checkNewSigningKey(cfg, name, pair); err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase("Passphrase: ", true)
+	if err != nil {
+		return err
+	}
+	key, err := tools.EncryptSigningKey(name, pair, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		if err := checkNewSigningKey(cfg, name, pair); err != nil {
+			return err
+		}
+		cfg.SigningKeys = append(cfg.SigningKeys, *key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stored signing key %s\n", name)
+	fmt.Printf("Public key: %s\n", key.PublicKey)
+	return nil
+}
+
+// checkNewSigningKey 检查名称和公钥是否已经保存
+func checkNewSigningKey(cfg *configs.ZerotierSwitcherProfile, name string, pair *tools.C25519KeyPair) error {
+	if cfg.FindSigningKey(name) != nil {
+		return fmt.Errorf("signing key (%s) exists", name)
+	}
+	for _, key := range cfg.SigningKeys {
+		if key.PublicKey == fmt.Sprintf("%x", pair.Public) {
+			return fmt.Errorf("the key is already stored as (%s)", key.Name)
+		}
+	}
+	return nil
+}
+
+func keyGenerateAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: key generate <name>")
+	}
+	pair, err := tools.GenerateC25519KeyPair()
+	if err != nil {
+		return err
+	}
+	return storeSigningKey(c, c.Args().First(), pair)
+}
+
+func keyImportAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return fmt.Errorf("usage: key import <name> <file>")
+	}
+	pair, err := tools.ReadC25519KeyPair(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	return storeSigningKey(c, c.Args().Get(0), pair)
+}
+
+func keyExportAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: key export -o <file> <name>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	key := cfg.FindSigningKey(c.Args().First())
+	if key == nil {
+		return fmt.Errorf("signing key (%s) not found", c.Args().First())
+	}
+	passphrase, err := readPassphrase("Passphrase: ", false)
+	if err != nil {
+		return err
+	}
+	pair, err := tools.DecryptSigningKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.String("out"), pair.Bytes(), 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Key pair written to %s\n", c.String("out"))
+	return nil
+}
+
+// shortKey 公钥的前16个字符，用于列表显示
+func shortKey(key string) string {
+	if len(key) > 16 {
+		return key[:16] + "..."
+	}
+	return key
+}
+
+func keyListAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	if len(cfg.SigningKeys) == 0 {
+		fmt.Println("No signing keys.")
+		return nil
+	}
+	for i := range cfg.SigningKeys {
+		key := &cfg.SigningKeys[i]
+		fmt.Printf("%s\t%s\tcreated %s\n", key.Name, shortKey(key.PublicKey), time.Unix(key.CreateTime, 0).Format(time.DateTime))
+		for _, planet := range tools.FindPlanetsSignedBy(cfg, key) {
+			fmt.Printf("  can sign: %s (world %d)\n", planet.Remark, planet.WorldId)
+		}
+	}
+	return nil
+}
+
+func keyRemoveAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: key remove <name>")
+	}
+	_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+		keys := make([]configs.ZerotierSigningKey, 0, len(cfg.SigningKeys))
+		for _, key := range cfg.SigningKeys {
+			if key.Name != c.Args().First() {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == len(cfg.SigningKeys) {
+			return fmt.Errorf("signing key (%s) not found", c.Args().First())
+		}
+		cfg.SigningKeys = keys
+		return nil
+	})
+	return err
+}
+
+// loadSigningKeyArg 读取签名密钥：mkworld格式的密钥文件，或保存的密钥名称(需要输入口令)
+func loadSigningKeyArg(cfg *configs.ZerotierSwitcherProfile, arg string) (*tools.C25519KeyPair, error) {
+	if s, err := os.Stat(arg); err == nil && !s.IsDir() {
+		return tools.ReadC25519KeyPair(arg)
+	}
+	key := cfg.FindSigningKey(arg)
+	if key == nil {
+		return nil, fmt.Errorf("signing key (%s) not found", arg)
+	}
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase of %s: ", key.Name), false)
+	if err != nil {
+		return nil, err
+	}
+	return tools.DecryptSigningKey(key, passphrase)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+func lintCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lint",
+		Usage:     "Check planets for common problems",
+		ArgsUsage: "<planet...>",
+		Description: "Each planet can be a file path, \"installed\" for the planet in use, or a stored planet\n" +
+			"referenced by remark or hash prefix. Exits with an error if any issue of severity error is found.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "as-planet", Usage: "Check the files as planets (implied for files named \"planet\")"},
+			&cli.BoolFlag{Name: "lenient", Usage: "Parse damaged files as far as possible and report every format problem"},
+		},
+		Action: lintAction,
+	}
+}
+
+func lintAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("usage: lint <planet...>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	failed := false
+	opts := tools.ParseOptions{Lenient: c.Bool("lenient")}
+	for _, arg := range c.Args().Slice() {
+		world, err := loadWorldArgWithOptions(cfg, arg, opts)
+		if err != nil {
+			fmt.Printf("%s: %v\n", arg, err)
+			failed = true
+			continue
+		}
+		asPlanet := c.Bool("as-planet") || arg == "installed" || filepath.Base(arg) == "planet"
+		issues := tools.LintWorld(world, asPlanet)
+		if len(issues) == 0 {
+			fmt.Printf("%s: ok\n", arg)
+			continue
+		}
+		fmt.Printf("%s:\n", arg)
+		for _, issue := range issues {
+			fmt.Printf("  %s\n", issue)
+		}
+		if tools.HasLintErrors(issues) {
+			failed = true
+		}
+	}
+	if failed {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func moonCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "moon",
+		Usage: "Create and install moons",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "Create and sign a moon for a root (replaces zerotier-idtool initmoon/genmoon)",
+				Description: "The moon is signed with the identity's own key unless --signing-key is given, so an\n" +
+					"identity.public file or string is enough when signing with a separate key.",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "identity", Aliases: []string{"i"}, Usage: "identity.secret / identity.public file or identity string of the root", Required: true},
+					&cli.StringSliceFlag{Name: "endpoint", Aliases: []string{"e"}, Usage: "Stable endpoint of the root as ip/port (repeatable)", Required: true},
+					&cli.StringFlag{Name: "signing-key", Aliases: []string{"k"}, Usage: "Stored signing key name or current.c25519 file"},
+					&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "Output .moon file (default: <id>.moon in the current directory)"},
+					&cli.StringFlag{Name: "definition", Usage: "Also write the moon definition (initmoon format, includes the signing secret)"},
+					&cli.BoolFlag{Name: "store", Usage: "Store the moon in the profile"},
+					&cli.StringFlag{Name: "remark", Usage: "Remark of the stored moon"},
+					&cli.BoolFlag{Name: "install", Usage: "Install the moon into moons.d and restart ZeroTier (requires root)"},
+				},
+				Action: moonCreateAction,
+			},
+			{
+				Name:        "install",
+				Usage:       "Install a moon into moons.d and restart ZeroTier (requires root)",
+				ArgsUsage:   "<moon>",
+				Description: "The moon can be a file path or a stored moon referenced by remark or hash prefix.",
+				Action:      moonInstallAction,
+			},
+		},
+	}
+}
+
+func moonCreateAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	identity, secret, err := loadIdentityArg(c.String("identity"))
+	if err != nil {
+		return err
+	}
+	var endpoints []tools.InetAddress
+	for _, text := range c.StringSlice("endpoint") {
+		ep, err := tools.ParseEndpoint(text)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	var signer *tools.C25519KeyPair
+	if c.String("signing-key") != "" {
+		signer, err = loadSigningKeyArg(cfg, c.String("signing-key"))
+	} else if secret != nil {
+		signer, err = tools.ParseC25519KeyPair(append(append([]byte{}, identity.PublicKey...), secret...))
+	} else {
+		err = fmt.Errorf("the identity has no secret, use identity.secret or --signing-key")
+	}
+	if err != nil {
+		return err
+	}
+
+	world, err := tools.CreateMoon(*identity, endpoints, signer)
+	if err != nil {
+		return err
+	}
+	out := c.String("out")
+	if out == "" {
+		out = tools.MoonFileName(world)
+	}
+	if err := os.WriteFile(out, world.RawData, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Moon %016x written to %s\n", world.ID, out)
+	fmt.Printf("Nodes join it with: zerotier-cli orbit %016x %016x\n", world.ID, world.ID)
+
+	if c.String("definition") != "" {
+		data, err := json.MarshalIndent(tools.MakeMoonDefinition(world, signer), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(c.String("definition"), data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Moon definition written to %s\n", c.String("definition"))
+	}
+
+	if c.Bool("store") {
+		var planet *configs.ZerotierPlanetFile
+		var isRevision bool
+		_, err := updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+			var err error
+			planet, isRevision, err = tools.StoreWorld(cfg, world, c.String("remark"))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if isRevision {
+			fmt.Printf("Stored as a revision of %s\n", planet.Remark)
+		} else {
+			fmt.Printf("Stored as %s\n", planet.Remark)
+		}
+	}
+
+	if c.Bool("install") {
+		return installMoon(cfg, world)
+	}
+	return nil
+}
+
+func moonInstallAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("usage: moon install <moon>")
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	world, err := loadWorldArg(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
+	return installMoon(cfg, world)
+}
+
+func installMoon(cfg *configs.ZerotierSwitcherProfile, world *tools.World) error {
+	if !tools.IsRunAsRoot() {
+		return fmt.Errorf("installing a moon requires root (administrator)")
+	}
+	return tools.InstallMoon(cfg, world.ToBase64(), func(step int, desc string) {
+		fmt.Println(desc)
+	})
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+
+	"github.com/urfave/cli/v2"
+)
+
+func resetCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "reset",
+		Usage: "Reset ZeroTier to the official Earth planet",
+		Description: "The original planet file is preserved byte for byte on first use. The official Earth planet\n" +
+			"is bundled with the program, or taken from the preserved original planet if it is Earth.\n" +
+			"With --original the preserved planet file is restored as it was, whatever it contains.",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "original", Usage: "Restore the preserved original planet file instead"},
+		},
+		Action: resetAction,
+	}
+}
+
+func resetAction(c *cli.Context) error {
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	if !tools.IsRunAsRoot() {
+		return fmt.Errorf("replacing the planet requires root (administrator)")
+	}
+	if _, err := tools.PreserveOriginalPlanet(cfg); err != nil {
+		return fmt.Errorf("preserve the original planet error: %v", err)
+	}
+
+	var data []byte
+	if c.Bool("original") {
+		if data, err = tools.ReadOriginalPlanet(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("restoring the original planet from %s\n", cfg.GetOriginalPlanetPath())
+	} else {
+		earth, source, err := tools.EarthWorld(cfg)
+		if err != nil {
+			return err
+		}
+		data = earth.RawData
+		fmt.Printf("resetting to the official Earth (%s)\n", source)
+		// keep Earth in the list so it can be activated again
+		_, err = updateProfile(c, func(cfg *configs.ZerotierSwitcherProfile) error {
+			if _, _, err := tools.StoreWorld(cfg, earth, "Earth"); err != nil {
+				// already in the list
+				return errNoChanges
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tools.ReplacePlanetAndJoinNetwork(cfg, base64.StdEncoding.EncodeToString(data), "", func(step int, desc string) {
+		fmt.Println(desc)
+	})
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Publish the planets of the profile as a signed catalog over HTTP",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "listen", Aliases: []string{"l"}, Value: ":9994", Usage: "Listen address"},
+			&cli.StringFlag{Name: "key", Aliases: []string{"k"}, Usage: "Team private key file (see `catalog keygen`)", Required: true},
+			&cli.StringFlag{Name: "name", Value: "zerotier-switcher", Usage: "Catalog name"},
+			&cli.StringFlag{Name: "tag", Aliases: []string{"t"}, Usage: "Only publish planets with this tag"},
+		},
+		Action: serveAction,
+	}
+}
+
+func serveAction(c *cli.Context) error {
+	key, err := tools.ReadCatalogKey(c.String("key"))
+	if err != nil {
+		return err
+	}
+	cfg, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+	index, _, err := tools.BuildCatalogIndex(cfg, c.String("name"), c.String("tag"))
+	if err != nil {
+		return err
+	}
+
+	host := c.String("listen")
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	fmt.Printf("Publishing %d planet(s) on %s\n", len(index.Planets), c.String("listen"))
+	fmt.Printf("Catalog: http://%s/%s\n", host, tools.CatalogIndexFileName)
+	fmt.Printf("Public key: %s\n", hex.EncodeToString(key.Public().(ed25519.PublicKey)))
+
+	server := &http.Server{
+		Addr: c.String("listen"),
+		Handler: &tools.CatalogServer{
+			Name:       c.String("name"),
+			Tag:        c.String("tag"),
+			Key:        key,
+			ConfigPath: c.String("config"),
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
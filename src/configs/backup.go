@@ -0,0 +1,195 @@
+package configs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackupRetention 默认保留的自动备份数量
+const DefaultBackupRetention = 20
+
+const backupFilePrefix = "profile."
+const backupFileSuffix = ".json"
+
+// ProfileBackup 自动备份的配置文件
+type ProfileBackup struct {
+	Name    string
+	Path    string
+	Time    time.Time
+	Profile *ZerotierSwitcherProfile // nil if the backup cannot be read
+	Err     error
+}
+
+// Describe 备份内容的概要
+func (b ProfileBackup) Describe() string {
+	if b.Err != nil {
+		return fmt.Sprintf("unreadable: %v", b.Err)
+	}
+	revisions := 0
+	remarks := make([]string, len(b.Profile.Planets))
+	for i, p := range b.Profile.Planets {
+		revisions += len(p.Revisions)
+		remarks[i] = p.Remark
+	}
+	desc := fmt.Sprintf("%d planets, %d old revisions, %d catalogs, %d signing keys",
+		len(b.Profile.Planets), revisions, len(b.Profile.Catalogs), len(b.Profile.SigningKeys))
+	if errs := b.Profile.PlanetErrors(); len(errs) > 0 {
+		desc += fmt.Sprintf(" (%d unreadable)", len(errs))
+	}
+	if len(remarks) > 0 {
+		desc += ": " + strings.Join(remarks, ", ")
+	}
+	return desc
+}
+
+// BackupRetention 保留的自动备份数量，0为默认值，负数为不备份
+func (c ZerotierSwitcherProfile) BackupRetention() int {
+	retention, err := strconv.Atoi(c.SettingValue("backups"))
+	if err != nil || retention == 0 {
+		return DefaultBackupRetention
+	}
+	return retention
+}
+
+// GetBackupDir 自动备份目录，位于配置文件所在目录下
+func (c ZerotierSwitcherProfile) GetBackupDir() string {
+	return filepath.Join(filepath.Dir(c.filePath), "backups")
+}
+
+// GetOriginalPlanetPath 首次使用时保存的原始planet文件
+func (c ZerotierSwitcherProfile) GetOriginalPlanetPath() string {
+	return filepath.Join(filepath.Dir(c.filePath), "original", "planet")
+}
+
+// backupBeforeWrite 写入配置前备份当前的配置文件，内容没有变化时不备份
+func (c ZerotierSwitcherProfile) backupBeforeWrite(data []byte) error {
+	if c.BackupRetention() < 0 && !c.forceBackup {
+		return nil
+	}
+	current, err := os.ReadFile(c.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+	return c.saveBackup(current)
+}
+
+// saveBackup 保存一份配置文件的备份，并删除超出保留数量的旧备份
+func (c ZerotierSwitcherProfile) saveBackup(content []byte) error {
+	dir := c.GetBackupDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// the profile may contain signing keys
+	for ms := time.Now().UnixMilli(); ; ms++ {
+		name := fmt.Sprintf("%s%d%s", backupFilePrefix, ms, backupFileSuffix)
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			// another backup in the same millisecond
+			continue
+		} else if err != nil {
+			return err
+		}
+		_, err = file.Write(content)
+		if cErr := file.Close(); err == nil {
+			err = cErr
+		}
+		if err != nil {
+			return err
+		}
+		break
+	}
+	if c.BackupRetention() < 0 {
+		// forced backups are kept when automatic backups are disabled
+		return nil
+	}
+	return c.pruneBackups()
+}
+
+// pruneBackups 删除超出保留数量的旧备份
+func (c ZerotierSwitcherProfile) pruneBackups() error {
+	backups, err := c.ListBackups(false)
+	if err != nil {
+		return err
+	}
+	for i := c.BackupRetention(); i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBackups 列出自动备份，按时间从新到旧排列，withContent为true时读取备份内容
+func (c ZerotierSwitcherProfile) ListBackups(withContent bool) ([]ProfileBackup, error) {
+	entries, err := os.ReadDir(c.GetBackupDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var backups []ProfileBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileSuffix) {
+			continue
+		}
+		ms, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		backup := ProfileBackup{Name: name, Path: filepath.Join(c.GetBackupDir(), name), Time: time.UnixMilli(ms)}
+		if withContent {
+			backup.Profile, backup.Err = ReadProfileFile(backup.Path, c.store)
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// LoadBackup 用备份的内容替换内存中的配置，下次写入时当前配置总是会先被备份，即使关闭了自动备份
+func (c *ZerotierSwitcherProfile) LoadBackup(backup ProfileBackup) error {
+	restored, err := ReadProfileFile(backup.Path, c.store)
+	if err != nil {
+		return err
+	}
+	if errs := restored.PlanetErrors(); len(errs) > 0 {
+		return fmt.Errorf("backup %s cannot be restored, %d planets are unreadable: %v", backup.Name, len(errs), errs[0])
+	}
+	restored.filePath = c.filePath
+	restored.loadedHash = c.loadedHash
+	restored.systemSettings = c.systemSettings
+	restored.envSettings = c.envSettings
+	// keep the current retention instead of the one saved in the backup
+	restored.Backups = c.Backups
+	// otherwise the restore cannot be undone
+	restored.forceBackup = true
+	*c = *restored
+	return nil
+}
+
+// RestoreBackup 用备份替换当前配置，当前配置总是会先被备份
+func (c *ZerotierSwitcherProfile) RestoreBackup(backup ProfileBackup) error {
+	restored := *c
+	if err := restored.LoadBackup(backup); err != nil {
+		return err
+	}
+	if err := restored.WriteAppConfig(); err != nil {
+		return err
+	}
+	*c = restored
+	return nil
+}
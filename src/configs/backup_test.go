@@ -0,0 +1,52 @@
+package configs
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreBackupWithBackupsDisabled(t *testing.T) {
+	cfg, err := ReadAppConfig(filepath.Join(t.TempDir(), "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	disabled := -1
+	cfg.Backups = &disabled
+	cfg.Theme = ThemeDark
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := cfg.ListBackups(false); len(backups) != 0 {
+		t.Fatalf("got %d automatic backups with backups disabled", len(backups))
+	}
+
+	old := *cfg
+	old.Theme = ThemeLight
+	data, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.saveBackup(data); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := cfg.ListBackups(false)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("got %d backups, %v", len(backups), err)
+	}
+
+	if err := cfg.RestoreBackup(backups[0]); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Theme != ThemeLight || cfg.BackupRetention() != -1 {
+		t.Fatalf("restored theme %q, retention %d", cfg.Theme, cfg.BackupRetention())
+	}
+	backups, err = cfg.ListBackups(true)
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("got %d backups after restoring, %v", len(backups), err)
+	}
+	// the profile replaced by the restore can be restored again
+	if backups[0].Err != nil || backups[0].Profile.Theme != ThemeDark {
+		t.Fatalf("safety backup has theme %q, %v", backups[0].Profile.Theme, backups[0].Err)
+	}
+}
@@ -0,0 +1,186 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	SourceDefault = "default"
+	SourceSystem  = "system"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀，例如 ZTS_API_PORT
+const EnvPrefix = "ZTS_"
+
+// EnvConfigPath 指定配置文件路径的环境变量，与 --config 相同
+const EnvConfigPath = EnvPrefix + "CONFIG"
+
+// SettingSource 配置项的有效值及其来源
+type SettingSource struct {
+	Setting *ProfileSetting
+	Value   string
+	Source  string // default, system, profile or env
+	Origin  string // file or environment variable the value came from
+}
+
+func (s SettingSource) String() string {
+	if s.Origin == "" {
+		return s.Source
+	}
+	return fmt.Sprintf("%s (%s)", s.Source, s.Origin)
+}
+
+// settingLayer 系统配置或环境变量中的配置项
+type settingLayer struct {
+	values  map[string]string
+	origins map[string]string
+}
+
+// GetSystemConfigPath 系统级默认配置文件位置
+func GetSystemConfigPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "zerotier-switcher", "config.json")
+	}
+	return "/etc/zerotier-switcher/config.json"
+}
+
+// EnvName 覆盖配置项的环境变量名
+func (s ProfileSetting) EnvName() string {
+	return EnvPrefix + strings.ToUpper(s.Key)
+}
+
+// UnknownEnvVars 不对应任何配置项的 ZTS_ 环境变量
+func UnknownEnvVars() []string {
+	var unknown []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvConfigPath {
+			continue
+		}
+		if FindProfileSetting(strings.ToLower(strings.TrimPrefix(name, EnvPrefix))) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// loadSettingLayers 读取系统配置和环境变量，只检查值的格式；目录、命令等是否存在由 SettingWarnings 报告
+func (c *ZerotierSwitcherProfile) loadSettingLayers() error {
+	system, err := readSystemConfig(GetSystemConfigPath())
+	if err != nil {
+		return err
+	}
+	env := settingLayer{values: map[string]string{}, origins: map[string]string{}}
+	for _, setting := range ProfileSettings {
+		if value, ok := os.LookupEnv(setting.EnvName()); ok {
+			env.values[setting.Key] = value
+			env.origins[setting.Key] = setting.EnvName()
+		}
+	}
+	for _, layer := range []*settingLayer{&system, &env} {
+		for key, value := range layer.values {
+			normalized, err := normalizeSetting(FindProfileSetting(key), value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", layer.origins[key], err)
+			}
+			layer.values[key] = normalized
+		}
+	}
+	c.systemSettings = system
+	c.envSettings = env
+	return nil
+}
+
+// readSystemConfig 读取系统配置，格式为配置项名称到值的JSON对象，文件不存在时为空
+func readSystemConfig(path string) (settingLayer, error) {
+	layer := settingLayer{values: map[string]string{}, origins: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return layer, nil
+	} else if err != nil {
+		return layer, fmt.Errorf("read system config error: %v", err)
+	}
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return layer, fmt.Errorf("parse system config (%s) error: %v", path, err)
+	}
+	for key, raw := range doc {
+		if FindProfileSetting(key) == nil {
+			return layer, fmt.Errorf("%s: unknown setting %s", path, key)
+		}
+		switch v := raw.(type) {
+		case string:
+			layer.values[key] = v
+		case json.Number:
+			layer.values[key] = v.String()
+		case bool:
+			layer.values[key] = map[bool]string{true: "yes", false: "no"}[v]
+		default:
+			return layer, fmt.Errorf("%s: %s must be a string, number or boolean", path, key)
+		}
+		layer.origins[key] = path
+	}
+	return layer, nil
+}
+
+// normalizeSetting 检查配置项的值的格式，返回设置后的有效值
+func normalizeSetting(setting *ProfileSetting, value string) (string, error) {
+	var scratch ZerotierSwitcherProfile
+	if err := setting.Set(&scratch, value); err != nil {
+		return "", err
+	}
+	return setting.Value(&scratch), nil
+}
+
+// EffectiveSetting 配置项的有效值：环境变量 > 配置文件 > 系统配置 > 默认值
+func (c ZerotierSwitcherProfile) EffectiveSetting(key string) SettingSource {
+	setting := FindProfileSetting(key)
+	if value, ok := c.envSettings.values[key]; ok {
+		return SettingSource{Setting: setting, Value: value, Source: SourceEnv, Origin: c.envSettings.origins[key]}
+	}
+	if value := setting.Get(&c); value != "" {
+		return SettingSource{Setting: setting, Value: value, Source: SourceProfile, Origin: c.filePath}
+	}
+	if value, ok := c.systemSettings.values[key]; ok {
+		return SettingSource{Setting: setting, Value: value, Source: SourceSystem, Origin: c.systemSettings.origins[key]}
+	}
+	return SettingSource{Setting: setting, Value: setting.Default(&c), Source: SourceDefault}
+}
+
+// EffectiveSettings 全部配置项的有效值
+func (c ZerotierSwitcherProfile) EffectiveSettings() []SettingSource {
+	settings := make([]SettingSource, len(ProfileSettings))
+	for i, setting := range ProfileSettings {
+		settings[i] = c.EffectiveSetting(setting.Key)
+	}
+	return settings
+}
+
+// SettingValue 配置项的有效值
+func (c ZerotierSwitcherProfile) SettingValue(key string) string {
+	return c.EffectiveSetting(key).Value
+}
+
+// SettingWarnings 检查正在使用的非默认值在当前系统上是否可用，例如目录或命令不存在
+func (c ZerotierSwitcherProfile) SettingWarnings() []ProfileIssue {
+	var issues []ProfileIssue
+	for _, source := range c.EffectiveSettings() {
+		if source.Source == SourceDefault || source.Setting.Check == nil {
+			continue
+		}
+		if err := source.Setting.Check(source.Value); err != nil {
+			issues = append(issues, ProfileIssue{Warning: true, Path: source.Setting.Key, Message: fmt.Sprintf("%v, set by %s", err, source)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,122 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrProfileChanged 配置文件在读取之后被其他程序修改
+var ErrProfileChanged = errors.New("the profile was changed by another program since it was loaded, reload it first")
+
+// profileLockTimeout 等待其他程序释放配置文件锁的时间
+const profileLockTimeout = 10 * time.Second
+
+// profileLock 配置文件的建议锁，保护读取-修改-写入的过程
+type profileLock struct {
+	file *os.File
+}
+
+// lockProfile 获取配置文件的排他锁(锁文件为 <profile>.lock)
+func lockProfile(path string) (*profileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(profileLockTimeout)
+	for {
+		locked, err := lockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock profile error: %v", err)
+		}
+		if locked {
+			return &profileLock{file: f}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("the profile is locked by another program")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (l *profileLock) Unlock() {
+	_ = unlockFile(l.file)
+	_ = l.file.Close()
+}
+
+// diskHash 配置文件当前内容的hash，文件不存在时为空
+func (c ZerotierSwitcherProfile) diskHash() (string, error) {
+	data, err := os.ReadFile(c.filePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return PlanetHash(data), nil
+}
+
+// ChangedOnDisk 配置文件是否在读取(或上次写入)之后被其他程序修改
+func (c ZerotierSwitcherProfile) ChangedOnDisk() bool {
+	hash, err := c.diskHash()
+	return err == nil && hash != "" && hash != c.loadedHash
+}
+
+// IgnoreExternalChanges 忽略其他程序的修改，下次写入时覆盖
+func (c *ZerotierSwitcherProfile) IgnoreExternalChanges() error {
+	hash, err := c.diskHash()
+	if err != nil {
+		return err
+	}
+	c.loadedHash = hash
+	return nil
+}
+
+// Reload 重新读取配置文件，放弃内存中的修改
+func (c *ZerotierSwitcherProfile) Reload() error {
+	fresh, err := ReadAppConfig(c.filePath)
+	if err != nil {
+		return err
+	}
+	*c = *fresh
+	return nil
+}
+
+// writeFileAtomic 先写入临时文件并同步到磁盘，再替换目标文件，避免留下写了一半的文件
+func writeFileAtomic(filePath string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(filePath)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+	// persist the rename, not supported on every platform
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
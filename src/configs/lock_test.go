@@ -0,0 +1,71 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUpdateProfileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if _, err := ReadAppConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := UpdateProfile(path, func(cfg *ZerotierSwitcherProfile) error {
+				cfg.Planets = append(cfg.Planets, testPlanet(fmt.Sprint(i), fmt.Sprintf("planet %d", i)))
+				return nil
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := ReadAppConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no update is lost
+	if len(cfg.Planets) != writers {
+		t.Fatalf("got %d planets, want %d", len(cfg.Planets), writers)
+	}
+}
+
+func TestUpdateProfileError(t *testing.T) {
+	cfg := newTestProfile(t, testPlanet("one", "planet one"))
+	failed := errors.New("failed")
+	_, err := UpdateProfile(cfg.ConfigPath(), func(cfg *ZerotierSwitcherProfile) error {
+		cfg.Planets = nil
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got error %v", err)
+	}
+	if cfg.ChangedOnDisk() {
+		t.Fatal("the profile was written although the update failed")
+	}
+	// a profile loaded before the update is not overwritten
+	if _, err := UpdateProfile(cfg.ConfigPath(), func(cfg *ZerotierSwitcherProfile) error {
+		cfg.Theme = ThemeDark
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Theme = ThemeLight
+	if err := cfg.WriteAppConfig(); !errors.Is(err, ErrProfileChanged) {
+		t.Fatalf("got error %v, want ErrProfileChanged", err)
+	}
+}
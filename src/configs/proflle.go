@@ -7,25 +7,197 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
+	"time"
 )
 
 type ZerotierSwitcherProfile struct {
 	filePath            string
-	Planets             []ZerotierPlanetFile `json:"planets"`
-	ZerotierProfilePath string               `json:"zerotier_profile_path"` // custom zerotier profile path
+	store               PlanetStore
+	loadedHash          string                  // hash of the profile file as read or last written
+	systemSettings      settingLayer            // settings from the system-wide config
+	envSettings         settingLayer            // settings from ZTS_* environment variables
+	planetErrors        []error                 // planets that could not be loaded from the planet store
+	forceBackup         bool                    // back up the current profile on the next write even if backups are disabled
+	Version             int                     `json:"version"` // schema version, see ProfileVersion
+	Planets             []ZerotierPlanetFile    `json:"planets"`
+	ZerotierProfilePath string                  `json:"zerotier_profile_path,omitempty"` // custom zerotier profile path
+	Catalogs            []ZerotierCatalog       `json:"catalogs,omitempty"`              // subscribed planet catalogs
+	SigningKeys         []ZerotierSigningKey    `json:"signing_keys,omitempty"`
+	Backups             *int                    `json:"backups,omitempty"` // number of automatic backups kept, -1 to disable
+	ZerotierCliPath     string                  `json:"zerotier_cli_path,omitempty"`
+	ZerotierService     string                  `json:"zerotier_service,omitempty"` // systemd unit, launchd plist or Windows service
+	ZerotierApiPort     *int                    `json:"zerotier_api_port,omitempty"`
+	Activate            ZerotierActivateOptions `json:"activate"`
+	Theme               string                  `json:"theme,omitempty"`
+}
+
+// ZerotierSigningKey 保存的C25519签名密钥，私钥使用口令加密
+type ZerotierSigningKey struct {
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`  // hex encoded C25519 public key
+	PrivateKey string `json:"private_key"` // base64 encoded nonce and AES-GCM encrypted private key
+	Salt       string `json:"salt"`        // hex encoded scrypt salt
+	CreateTime int64  `json:"create_time"`
+}
+
+// ZerotierCatalog 订阅的planet目录
+type ZerotierCatalog struct {
+	Name      string `json:"name"`
+	Source    string `json:"source"`     // http(s) url of the catalog, or a local directory
+	PublicKey string `json:"public_key"` // hex encoded ed25519 team key
+	ETag      string `json:"etag"`
+	LastSync  int64  `json:"last_sync"`
 }
 
 type ZerotierPlanetFile struct {
-	Hash         string `json:"hash"`
-	Remark       string `json:"remark"` // remark text (view)
-	Data         string `json:"data"`   // base64 encoded text
+	Hash         string `json:"hash"`           // sha256 of the raw planet file, its name in the planet store
+	Remark       string `json:"remark"`         // remark text (view)
+	Data         string `json:"-"`              // base64 encoded planet, loaded from the planet store
+	InlineData   string `json:"data,omitempty"` // base64 encoded planet embedded in portable backups and older profiles
 	CreateTime   uint64 `json:"create_time"`
 	WorldId      uint64 `json:"world_id"`
 	WorldType    uint8  `json:"world_type"` // (1=Planet, 127=Moon)
 	RootIdentity string `json:"root_identity"`
 	RootEndpoint string `json:"root_endpoint"` // Ip address of the planet file (view)
 
-	AutoJoinNetwork string `json:"auto_join_network"`
+	AutoJoinNetwork string   `json:"auto_join_network"`
+	Networks        []string `json:"networks,omitempty"` // networks announced by the catalog
+	Catalog         string   `json:"catalog,omitempty"`  // name of the catalog managing this entry
+	CatalogEntry    string   `json:"catalog_entry,omitempty"`
+	Retired         bool     `json:"retired,omitempty"` // removed from the catalog but still in use
+	Tags            []string `json:"tags,omitempty"`
+
+	Revisions      []ZerotierPlanetRevision `json:"revisions,omitempty"`       // older revisions of the same world
+	PinnedRevision string                   `json:"pinned_revision,omitempty"` // hash of the revision used for activation
+}
+
+// ZerotierPlanetRevision 同一个world的历史版本
+type ZerotierPlanetRevision struct {
+	Hash         string `json:"hash"`
+	Data         string `json:"-"`
+	InlineData   string `json:"data,omitempty"`
+	CreateTime   uint64 `json:"create_time"`
+	RootIdentity string `json:"root_identity"`
+	RootEndpoint string `json:"root_endpoint"`
+	ReplacedTime int64  `json:"replaced_time"` // when a newer revision took its place
+}
+
+// HasTag 判断planet是否带有指定标签
+func (p ZerotierPlanetFile) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReadOnly 由目录管理的planet不允许手动修改
+func (p ZerotierPlanetFile) IsReadOnly() bool {
+	return p.Catalog != ""
+}
+
+// HeadRevision 当前(最新)版本
+func (p ZerotierPlanetFile) HeadRevision() ZerotierPlanetRevision {
+	return ZerotierPlanetRevision{
+		Hash:         p.Hash,
+		Data:         p.Data,
+		CreateTime:   p.CreateTime,
+		RootIdentity: p.RootIdentity,
+		RootEndpoint: p.RootEndpoint,
+	}
+}
+
+// AllRevisions 全部版本，按时间从新到旧排列
+func (p ZerotierPlanetFile) AllRevisions() []ZerotierPlanetRevision {
+	revisions := append([]ZerotierPlanetRevision{p.HeadRevision()}, p.Revisions...)
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].CreateTime > revisions[j].CreateTime
+	})
+	return revisions
+}
+
+// FindRevision 按hash查找版本
+func (p ZerotierPlanetFile) FindRevision(hash string) *ZerotierPlanetRevision {
+	for _, rev := range p.AllRevisions() {
+		if rev.Hash == hash {
+			return &rev
+		}
+	}
+	return nil
+}
+
+// ActiveRevision 激活时使用的版本：固定的版本，否则为最新版本
+func (p ZerotierPlanetFile) ActiveRevision() ZerotierPlanetRevision {
+	if p.PinnedRevision != "" {
+		if rev := p.FindRevision(p.PinnedRevision); rev != nil {
+			return *rev
+		}
+	}
+	return p.HeadRevision()
+}
+
+// AddRevision 添加同一个world的版本，比当前版本新的会成为新的当前版本
+func (p *ZerotierPlanetFile) AddRevision(rev ZerotierPlanetRevision) bool {
+	if p.FindRevision(rev.Hash) != nil {
+		return false
+	}
+	if rev.CreateTime > p.CreateTime {
+		head := p.HeadRevision()
+		head.ReplacedTime = time.Now().Unix()
+		p.Revisions = append(p.Revisions, head)
+		p.Hash = rev.Hash
+		p.Data = rev.Data
+		p.CreateTime = rev.CreateTime
+		p.RootIdentity = rev.RootIdentity
+		p.RootEndpoint = rev.RootEndpoint
+	} else {
+		p.Revisions = append(p.Revisions, rev)
+	}
+	return true
+}
+
+// FindPlanet 按hash前缀或备注名查找planet
+func (c *ZerotierSwitcherProfile) FindPlanet(ref string) (*ZerotierPlanetFile, error) {
+	var found *ZerotierPlanetFile
+	for i := range c.Planets {
+		p := &c.Planets[i]
+		if p.Remark == ref {
+			return p, nil
+		}
+		if len(ref) >= 4 && strings.HasPrefix(p.Hash, strings.ToLower(ref)) {
+			if found != nil {
+				return nil, fmt.Errorf("planet reference (%s) is ambiguous", ref)
+			}
+			found = p
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("planet (%s) not found", ref)
+	}
+	return found, nil
+}
+
+// FindCatalog 按名称查找订阅的目录
+func (c *ZerotierSwitcherProfile) FindCatalog(name string) *ZerotierCatalog {
+	for i := range c.Catalogs {
+		if c.Catalogs[i].Name == name {
+			return &c.Catalogs[i]
+		}
+	}
+	return nil
+}
+
+// FindSigningKey 按名称查找签名密钥
+func (c *ZerotierSwitcherProfile) FindSigningKey(name string) *ZerotierSigningKey {
+	for i := range c.SigningKeys {
+		if c.SigningKeys[i].Name == name {
+			return &c.SigningKeys[i]
+		}
+	}
+	return nil
 }
 
 // GetDefaultConfigPath 获取当前程序的配置文件默认路径
@@ -43,7 +215,7 @@ func GetDefaultConfigPath() string {
 
 // GetPlanetFilePath 获取Zerotier的planet文件位置
 func GetPlanetFilePath(cfg *ZerotierSwitcherProfile) string {
-	return path.Join(cfg.ZerotierProfilePath, "planet")
+	return path.Join(cfg.ZerotierHome(), "planet")
 }
 
 func GetZerotierProfileFolder() (string, error) {
@@ -60,36 +232,117 @@ func GetZerotierProfileFolder() (string, error) {
 }
 
 func GetDefaultZerotierSwitcherProfile(path string) ZerotierSwitcherProfile {
-	profileFolder, _ := GetZerotierProfileFolder()
 	return ZerotierSwitcherProfile{
-		filePath:            path,
-		Planets:             []ZerotierPlanetFile{},
-		ZerotierProfilePath: profileFolder,
+		filePath: path,
+		store:    PlanetStoreOf(path),
+		Planets:  []ZerotierPlanetFile{},
 	}
 }
 
-// ReadAppConfig 读取配置
+// ReadAppConfig 读取配置，旧版本的配置会先备份再升级
 func ReadAppConfig(path string) (*ZerotierSwitcherProfile, error) {
+	lock, err := lockProfile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	return readAppConfig(path)
+}
+
+func readAppConfig(path string) (*ZerotierSwitcherProfile, error) {
 	data, err := os.ReadFile(path)
 	cfg := GetDefaultZerotierSwitcherProfile(path)
+	if err := cfg.loadSettingLayers(); err != nil {
+		return nil, err
+	}
 	if os.IsNotExist(err) {
-		err = cfg.WriteAppConfig()
+		err = cfg.writeLockedAppConfig(true)
 		return &cfg, err
 	} else if err != nil {
 		return nil, err
 	}
+	cfg.loadedHash = PlanetHash(data)
 
-	err = json.Unmarshal(data, &cfg)
+	migrated, version, err := migrateProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("read profile (%s) error: %v", path, err)
+	}
+	if err = json.Unmarshal(migrated, &cfg); err != nil {
+		return &cfg, err
+	}
 	cfg.filePath = path
+	inlined, planetErrors := cfg.loadPlanets()
+	cfg.planetErrors = planetErrors
+	if version < ProfileVersion {
+		if err := cfg.saveBackup(data); err != nil {
+			return &cfg, fmt.Errorf("backup profile before upgrading error: %v", err)
+		}
+		return &cfg, cfg.writeLockedAppConfig(false)
+	}
+	if inlined {
+		// a portable backup used as the profile
+		err = cfg.writeLockedAppConfig(true)
+	}
 	return &cfg, err
 }
 
-func (c ZerotierSwitcherProfile) SetConfigPath(path string) {
+func (c *ZerotierSwitcherProfile) SetConfigPath(path string) {
 	c.filePath = path
 }
 
-// WriteAppConfig 写入配置
-func (c ZerotierSwitcherProfile) WriteAppConfig() error {
+// PlanetErrors 读取配置时无法从planet存储读取的条目，这些条目没有数据
+func (c ZerotierSwitcherProfile) PlanetErrors() []error {
+	return c.planetErrors
+}
+
+// ConfigPath 配置文件路径
+func (c ZerotierSwitcherProfile) ConfigPath() string {
+	return c.filePath
+}
+
+// PlanetStore 保存planet文件的存储
+func (c ZerotierSwitcherProfile) PlanetStore() PlanetStore {
+	return c.store
+}
+
+// WriteAppConfig 写入配置，写入前自动备份；配置文件在读取之后被其他程序修改时返回 ErrProfileChanged
+func (c *ZerotierSwitcherProfile) WriteAppConfig() error {
+	lock, err := lockProfile(c.filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return c.writeLockedAppConfig(true)
+}
+
+// UpdateProfile 在持有配置文件锁期间读取配置、调用update修改并写入，update返回错误时不写入
+func UpdateProfile(path string, update func(cfg *ZerotierSwitcherProfile) error) (*ZerotierSwitcherProfile, error) {
+	lock, err := lockProfile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	cfg, err := readAppConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.writeLockedAppConfig(true)
+}
+
+// writeLockedAppConfig 写入配置，调用前需要持有配置文件锁
+func (c *ZerotierSwitcherProfile) writeLockedAppConfig(backup bool) error {
+	if hash, err := c.diskHash(); err != nil {
+		return err
+	} else if hash != "" && hash != c.loadedHash {
+		return ErrProfileChanged
+	}
+	c.Version = ProfileVersion
+	if err := c.savePlanets(); err != nil {
+		return fmt.Errorf("save planets error: %v", err)
+	}
 	// 获取配置文件路径
 	data, err := json.MarshalIndent(c, "", "\t")
 	if err != nil {
@@ -102,13 +355,34 @@ func (c ZerotierSwitcherProfile) WriteAppConfig() error {
 		return err
 	}
 
-	return os.WriteFile(c.filePath, data, 0644)
+	if backup {
+		if err := c.backupBeforeWrite(data); err != nil {
+			return fmt.Errorf("backup profile error: %v", err)
+		}
+	}
+	if err := writeConfigFile(c.filePath, data, c.fileMode()); err != nil {
+		return err
+	}
+	c.loadedHash = PlanetHash(data)
+	c.forceBackup = false
+	// the profile is saved, unreferenced planets are removed again on the next write
+	_ = c.prunePlanetStore(data)
+	return nil
+}
+
+// fileMode 保存了签名密钥的配置只允许当前用户读取
+func (c ZerotierSwitcherProfile) fileMode() os.FileMode {
+	if len(c.SigningKeys) > 0 {
+		return 0600
+	}
+	return 0644
 }
 
-// WriteAppConfigWithPath 写入配置(到指定路径)
+// WriteAppConfigWithPath 写入配置(到指定路径)，planet数据内嵌在文件中，可以单独导入
 func (c ZerotierSwitcherProfile) WriteAppConfigWithPath(filePath string) error {
 	// 获取配置文件路径
-	data, err := json.MarshalIndent(c, "", "\t")
+	c.Version = ProfileVersion
+	data, err := json.MarshalIndent(c.withInlineData(), "", "\t")
 	if err != nil {
 		return err
 	}
@@ -119,5 +393,28 @@ func (c ZerotierSwitcherProfile) WriteAppConfigWithPath(filePath string) error {
 		return err
 	}
 
-	return os.WriteFile(filePath, data, 0644)
+	return writeConfigFile(filePath, data, c.fileMode())
+}
+
+// writeConfigFile 原子地写入配置文件，已存在的文件也会更新权限
+func writeConfigFile(filePath string, data []byte, mode os.FileMode) error {
+	return writeFileAtomic(filePath, data, mode)
+}
+
+// ReadProfileFile 读取备份等配置文件，不会修改文件或planet存储；没有内嵌的planet从指定的存储中读取
+func ReadProfileFile(path string, store PlanetStore) (*ZerotierSwitcherProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err = migrateProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("read profile (%s) error: %v", path, err)
+	}
+	cfg := ZerotierSwitcherProfile{filePath: path, store: store}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse profile (%s) error: %v", path, err)
+	}
+	_, cfg.planetErrors = cfg.loadPlanets()
+	return &cfg, nil
 }
@@ -0,0 +1,291 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ProfileVersion 当前的配置文件结构版本
+//
+//	0: no version field, planets embedded as base64
+//	1: planets kept in the planet store, referenced by SHA-256
+//	2: settings stored only when set explicitly
+const ProfileVersion = 2
+
+// profileMigration 把配置从 From 版本升级到下一个版本
+type profileMigration struct {
+	From        int
+	Description string
+	Migrate     func(doc map[string]interface{}) error
+}
+
+var profileMigrations = []profileMigration{
+	{From: 0, Description: "reference planets by the SHA-256 of their content", Migrate: migrateInlinePlanets},
+	{From: 1, Description: "store only the settings that were set explicitly", Migrate: migrateImplicitSettings},
+}
+
+// migrateProfile 按顺序执行升级，返回升级后的内容和原来的版本；不支持比当前程序更新的版本。
+// 升级只修改内容，不会写入planet存储，读取备份等文件没有副作用
+func migrateProfile(data []byte) ([]byte, int, error) {
+	doc, err := decodeProfileDocument(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := profileDocumentVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > ProfileVersion {
+		return nil, version, fmt.Errorf("profile version %d is newer than the supported version %d, please upgrade zerotier-switcher", version, ProfileVersion)
+	}
+	if version == ProfileVersion {
+		return data, version, nil
+	}
+	for _, migration := range profileMigrations {
+		if migration.From < version {
+			continue
+		}
+		if err := migration.Migrate(doc); err != nil {
+			return nil, version, fmt.Errorf("upgrade profile from version %d (%s) error: %v", migration.From, migration.Description, err)
+		}
+	}
+	doc["version"] = json.Number(fmt.Sprint(ProfileVersion))
+	migrated, err := json.Marshal(doc)
+	return migrated, version, err
+}
+
+// decodeProfileDocument 解析为通用的JSON对象，数字保持原样(world id等可能超出float64的精度)
+func decodeProfileDocument(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	doc := map[string]interface{}{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse profile error: %v", err)
+	}
+	return doc, nil
+}
+
+func profileDocumentVersion(doc map[string]interface{}) (int, error) {
+	value, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid profile version %v", value)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid profile version %v", value)
+	}
+	return int(version), nil
+}
+
+// migrateInlinePlanets 版本0 -> 1: hash改为内容的SHA-256，内嵌的数据在配置保存时移动到planet存储
+func migrateInlinePlanets(doc map[string]interface{}) error {
+	planets, _ := doc["planets"].([]interface{})
+	for _, item := range planets {
+		planet, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		renamed := map[string]string{}
+		if err := rehashInlinePlanet(planet, renamed); err != nil {
+			return err
+		}
+		revisions, _ := planet["revisions"].([]interface{})
+		for _, revItem := range revisions {
+			if rev, ok := revItem.(map[string]interface{}); ok {
+				if err := rehashInlinePlanet(rev, renamed); err != nil {
+					return err
+				}
+			}
+		}
+		if pinned, ok := planet["pinned_revision"].(string); ok {
+			if hash, ok := renamed[pinned]; ok {
+				planet["pinned_revision"] = hash
+			}
+		}
+	}
+	return nil
+}
+
+func rehashInlinePlanet(entry map[string]interface{}, renamed map[string]string) error {
+	data, _ := entry["data"].(string)
+	if data == "" {
+		return nil
+	}
+	oldHash, _ := entry["hash"].(string)
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("planet %s: %v", shortHash(oldHash), err)
+	}
+	hash := PlanetHash(raw)
+	renamed[oldHash] = hash
+	entry["hash"] = hash
+	return nil
+}
+
+// migrateImplicitSettings 版本1 -> 2: 旧版本创建配置时总是保存ZeroTier数据目录，与默认目录相同时视为未设置，
+// 否则会覆盖系统配置中的值
+func migrateImplicitSettings(doc map[string]interface{}) error {
+	home, _ := doc["zerotier_profile_path"].(string)
+	if folder, _ := GetZerotierProfileFolder(); home == "" || home == folder {
+		delete(doc, "zerotier_profile_path")
+	}
+	return nil
+}
+
+// ProfileIssue 配置文件检查发现的问题
+type ProfileIssue struct {
+	Warning bool
+	Path    string // JSON path of the field, e.g. planets[0].remark
+	Message string
+}
+
+func (i ProfileIssue) String() string {
+	severity := "error"
+	if i.Warning {
+		severity = "warning"
+	}
+	if i.Path == "" {
+		return fmt.Sprintf("%s %s", severity, i.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", severity, i.Path, i.Message)
+}
+
+// ValidateProfileData 检查配置文件的结构：版本、未知字段、planet数据以及条目之间的引用
+func ValidateProfileData(data []byte, store PlanetStore) ([]ProfileIssue, *ZerotierSwitcherProfile) {
+	var issues []ProfileIssue
+	add := func(path, format string, args ...interface{}) {
+		issues = append(issues, ProfileIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	doc, err := decodeProfileDocument(data)
+	if err != nil {
+		add("", "%v", err)
+		return issues, nil
+	}
+	version, err := profileDocumentVersion(doc)
+	if err != nil {
+		add("version", "%v", err)
+	} else if version > ProfileVersion {
+		add("version", "version %d is newer than the supported version %d", version, ProfileVersion)
+		return issues, nil
+	} else if version < ProfileVersion {
+		issues = append(issues, ProfileIssue{Warning: true, Path: "version", Message: fmt.Sprintf("version %d will be upgraded to %d when the profile is opened", version, ProfileVersion)})
+	}
+	for _, field := range unknownFields(doc, reflect.TypeOf(ZerotierSwitcherProfile{}), "") {
+		issues = append(issues, ProfileIssue{Warning: true, Path: field, Message: "unknown field, it will be dropped when the profile is saved"})
+	}
+
+	cfg := ZerotierSwitcherProfile{store: store}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		add("", "%v", err)
+		return issues, nil
+	}
+	for i := range ProfileSettings {
+		setting := &ProfileSettings[i]
+		if value := setting.Get(&cfg); value != "" {
+			if _, err := normalizeSetting(setting, value); err != nil {
+				add(setting.Key, "%v", err)
+			}
+		}
+	}
+	issues = append(issues, cfg.SettingWarnings()...)
+	_, loadErrors := cfg.loadPlanets()
+	for _, err := range loadErrors {
+		add("planets", "%v", err)
+	}
+
+	seen := map[string]string{}
+	for i, p := range cfg.Planets {
+		path := fmt.Sprintf("planets[%d]", i)
+		for _, rev := range p.AllRevisions() {
+			if other, ok := seen[rev.Hash]; ok {
+				add(path, "revision %s is also stored in %s", shortHash(rev.Hash), other)
+			}
+			seen[rev.Hash] = path
+		}
+		if p.PinnedRevision != "" && p.FindRevision(p.PinnedRevision) == nil {
+			add(path+".pinned_revision", "revision %s not found", shortHash(p.PinnedRevision))
+		}
+		if p.Catalog != "" && cfg.FindCatalog(p.Catalog) == nil {
+			add(path+".catalog", "catalog %s is not subscribed", p.Catalog)
+		}
+		if p.AutoJoinNetwork != "" && len(p.AutoJoinNetwork) != 16 {
+			add(path+".auto_join_network", "network id %q must have 16 hex digits", p.AutoJoinNetwork)
+		}
+	}
+	names := map[string]bool{}
+	for i, catalog := range cfg.Catalogs {
+		if names["catalog:"+catalog.Name] {
+			add(fmt.Sprintf("catalogs[%d]", i), "duplicated catalog %s", catalog.Name)
+		}
+		names["catalog:"+catalog.Name] = true
+	}
+	for i, key := range cfg.SigningKeys {
+		if names["key:"+key.Name] {
+			add(fmt.Sprintf("signing_keys[%d]", i), "duplicated signing key %s", key.Name)
+		}
+		names["key:"+key.Name] = true
+	}
+	return issues, &cfg
+}
+
+// unknownFields 列出结构体中没有定义的JSON字段
+func unknownFields(value interface{}, typ reflect.Type, path string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var fields []string
+	switch typ.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		known := map[string]reflect.Type{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			known[name] = field.Type
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			fieldType, ok := known[key]
+			if !ok {
+				fields = append(fields, fieldPath)
+				continue
+			}
+			fields = append(fields, unknownFields(obj[key], fieldType, fieldPath)...)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			fields = append(fields, unknownFields(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,226 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// v0Contents profile-v0.json 中内嵌的planet内容
+var v0Contents = map[string]string{
+	"Earth":     "earth planet, second revision",
+	"Earth v1":  "earth planet, first revision",
+	"Team moon": "team moon",
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkV0Migrated 检查 profile-v0.json 升级后的内容
+func checkV0Migrated(t *testing.T, doc map[string]interface{}) {
+	t.Helper()
+	planets := doc["planets"].([]interface{})
+	earth := planets[0].(map[string]interface{})
+	moon := planets[1].(map[string]interface{})
+	revision := earth["revisions"].([]interface{})[0].(map[string]interface{})
+	for _, tt := range []struct {
+		name  string
+		entry map[string]interface{}
+	}{
+		{"Earth", earth}, {"Earth v1", revision}, {"Team moon", moon},
+	} {
+		if hash := tt.entry["hash"]; hash != PlanetHash([]byte(v0Contents[tt.name])) {
+			t.Errorf("%s: got hash %v", tt.name, hash)
+		}
+	}
+	if earth["pinned_revision"] != revision["hash"] {
+		t.Errorf("pinned revision %v does not follow the revision hash %v", earth["pinned_revision"], revision["hash"])
+	}
+	if moon["world_id"].(json.Number).String() != "18446744073709551615" {
+		t.Errorf("world id changed to %v", moon["world_id"])
+	}
+}
+
+func TestProfileMigrationSteps(t *testing.T) {
+	// expected content after each migration, keyed by the version it upgrades from
+	checks := map[int]func(t *testing.T, doc map[string]interface{}){
+		0: func(t *testing.T, doc map[string]interface{}) {
+			checkV0Migrated(t, doc)
+			// the data stays inline, it is moved to the planet store when the profile is saved
+			earth := doc["planets"].([]interface{})[0].(map[string]interface{})
+			if earth["data"] == nil {
+				t.Error("embedded data was dropped")
+			}
+		},
+		1: func(t *testing.T, doc map[string]interface{}) {
+			// the fixture stores the default home of the test platform
+			if home, ok := doc["zerotier_profile_path"]; ok && home == defaultHome(t) {
+				t.Errorf("the default ZeroTier home %v is still stored", home)
+			}
+		},
+	}
+	doc, err := decodeProfileDocument(readFixture(t, "profile-v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range profileMigrations {
+		t.Run(migration.Description, func(t *testing.T) {
+			check, ok := checks[migration.From]
+			if !ok {
+				t.Fatalf("no test for the migration from version %d", migration.From)
+			}
+			if err := migration.Migrate(doc); err != nil {
+				t.Fatal(err)
+			}
+			check(t, doc)
+		})
+	}
+	if len(profileMigrations) != ProfileVersion {
+		t.Fatalf("%d migrations for version %d", len(profileMigrations), ProfileVersion)
+	}
+}
+
+func defaultHome(t *testing.T) string {
+	t.Helper()
+	folder, err := GetZerotierProfileFolder()
+	if err != nil {
+		t.Skip(err)
+	}
+	return folder
+}
+
+func TestMigrateImplicitSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		home interface{}
+		want interface{}
+	}{
+		{name: "default home", home: defaultHome(t), want: nil},
+		{name: "empty home", home: "", want: nil},
+		{name: "custom home", home: "/opt/zerotier", want: "/opt/zerotier"},
+		{name: "no home", home: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{"version": json.Number("1"), "theme": "dark"}
+			if tt.home != nil {
+				doc["zerotier_profile_path"] = tt.home
+			}
+			if err := migrateImplicitSettings(doc); err != nil {
+				t.Fatal(err)
+			}
+			if got := doc["zerotier_profile_path"]; got != tt.want {
+				t.Fatalf("got home %v, want %v", got, tt.want)
+			}
+			if doc["theme"] != "dark" {
+				t.Fatal("other settings changed")
+			}
+		})
+	}
+}
+
+func TestMigrateProfile(t *testing.T) {
+	v0 := readFixture(t, "profile-v0.json")
+	tests := []struct {
+		name        string
+		data        []byte
+		wantVersion int
+		wantErr     string
+	}{
+		{name: "v0", data: v0, wantVersion: 0},
+		{name: "v1", data: []byte(`{"version":1,"planets":[],"zerotier_profile_path":"/opt/zerotier"}`), wantVersion: 1},
+		{name: "current", data: []byte(`{"version":2,"planets":[]}`), wantVersion: 2},
+		{name: "newer", data: []byte(`{"version":3,"planets":[]}`), wantErr: "newer than the supported version"},
+		{name: "invalid version", data: []byte(`{"version":"1"}`), wantErr: "invalid profile version"},
+		{name: "broken data", data: []byte(`{"planets":[{"hash":"x","data":"not base64!"}]}`), wantErr: "upgrade profile from version 0"},
+		{name: "not json", data: []byte(`planets`), wantErr: "parse profile error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, version, err := migrateProfile(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tt.wantVersion {
+				t.Fatalf("got version %d, want %d", version, tt.wantVersion)
+			}
+			doc, err := decodeProfileDocument(migrated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := profileDocumentVersion(doc); got != ProfileVersion {
+				t.Fatalf("migrated to version %d", got)
+			}
+			if tt.wantVersion == 0 {
+				checkV0Migrated(t, doc)
+			}
+		})
+	}
+}
+
+func TestReadAppConfigUpgradesV0(t *testing.T) {
+	v0 := readFixture(t, "profile-v0.json")
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(path, v0, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReadAppConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.PlanetErrors()) != 0 {
+		t.Fatal(cfg.PlanetErrors())
+	}
+
+	// planets are moved to the planet store and loaded from it
+	for name, content := range v0Contents {
+		raw, err := cfg.PlanetStore().Get(PlanetHash([]byte(content)))
+		if err != nil || string(raw) != content {
+			t.Fatalf("%s: got %q, %v", name, raw, err)
+		}
+	}
+	earth := cfg.Planets[0]
+	if earth.ActiveRevision().Hash != PlanetHash([]byte(v0Contents["Earth v1"])) {
+		t.Fatalf("pinned revision %s", earth.PinnedRevision)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := decodeProfileDocument(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := profileDocumentVersion(doc); version != ProfileVersion {
+		t.Fatalf("saved version %d", version)
+	}
+	if bytes.Contains(data, []byte(`"data"`)) {
+		t.Fatal("planets are still embedded in the profile")
+	}
+	checkV0Migrated(t, doc)
+
+	// the profile before the upgrade is backed up unchanged
+	backups, err := cfg.ListBackups(false)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("got %d backups, %v", len(backups), err)
+	}
+	if backup, _ := os.ReadFile(backups[0].Path); !bytes.Equal(backup, v0) {
+		t.Fatal("the backup differs from the original profile")
+	}
+}
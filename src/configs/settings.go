@@ -0,0 +1,345 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ThemeAuto  = "auto"
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemePlain = "plain"
+)
+
+var Themes = []string{ThemeAuto, ThemeDark, ThemeLight, ThemePlain}
+
+// DefaultZerotierApiPort ZeroTier本地API的默认端口
+const DefaultZerotierApiPort = 9993
+
+// DefaultJoinDelay 重启服务后等待多久再加入网络
+const DefaultJoinDelay = 3
+
+// ZerotierActivateOptions 激活planet时的默认选项，nil为未设置
+type ZerotierActivateOptions struct {
+	SkipRestart *bool `json:"skip_restart,omitempty"` // only replace the planet file, the service is restarted manually
+	SkipJoin    *bool `json:"skip_join,omitempty"`    // do not join the auto join network
+	JoinDelay   *int  `json:"join_delay,omitempty"`   // seconds to wait for the service before joining
+}
+
+// ProfileSetting 可以在设置页面修改的配置项
+type ProfileSetting struct {
+	Key     string
+	Name    string
+	Desc    string
+	Choices []string                                         // fixed values, cycled instead of typed
+	Get     func(c *ZerotierSwitcherProfile) string          // stored value, empty if not set, even when it equals the default
+	Default func(c *ZerotierSwitcherProfile) string          // value used when nothing is stored
+	Set     func(c *ZerotierSwitcherProfile, v string) error // checks the syntax and stores the value, empty unsets it
+	Check   func(v string) error                             // checks that the value works on this system, e.g. the directory exists
+}
+
+// Apply 设置页面修改配置项：检查格式并保存，再检查该值在当前系统上是否可用
+func (s ProfileSetting) Apply(c *ZerotierSwitcherProfile, v string) error {
+	if err := s.Set(c, v); err != nil {
+		return err
+	}
+	if stored := s.Get(c); stored != "" && s.Check != nil {
+		return s.Check(stored)
+	}
+	return nil
+}
+
+// Value 配置项的当前值，未设置时为默认值
+func (s ProfileSetting) Value(c *ZerotierSwitcherProfile) string {
+	if v := s.Get(c); v != "" {
+		return v
+	}
+	return s.Default(c)
+}
+
+var ProfileSettings = []ProfileSetting{
+	{
+		Key:  "zerotier_home",
+		Name: "ZeroTier home",
+		Desc: "directory holding the planet file, identity and moons.d",
+		Get:  func(c *ZerotierSwitcherProfile) string { return c.ZerotierProfilePath },
+		Default: func(c *ZerotierSwitcherProfile) string {
+			folder, _ := GetZerotierProfileFolder()
+			return folder
+		},
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			if v == "" {
+				c.ZerotierProfilePath = ""
+				return nil
+			}
+			home, err := filepath.Abs(v)
+			if err != nil {
+				return err
+			}
+			c.ZerotierProfilePath = home
+			return nil
+		},
+		Check: func(v string) error {
+			if info, err := os.Stat(v); err != nil || !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", v)
+			}
+			if _, err := os.Stat(filepath.Join(v, "planet")); err != nil {
+				return fmt.Errorf("no planet file in %s", v)
+			}
+			return nil
+		},
+	},
+	{
+		Key:  "zerotier_cli",
+		Name: "zerotier-cli",
+		Desc: "command used to join networks",
+		Get:  func(c *ZerotierSwitcherProfile) string { return c.ZerotierCliPath },
+		Default: func(c *ZerotierSwitcherProfile) string {
+			if runtime.GOOS == "windows" {
+				return filepath.Join(os.Getenv("ProgramFiles"), "ZeroTier", "One", "zerotier-cli.bat")
+			}
+			return "zerotier-cli"
+		},
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			c.ZerotierCliPath = v
+			return nil
+		},
+		Check: func(v string) error {
+			if _, err := exec.LookPath(v); err != nil {
+				return fmt.Errorf("%s is not an executable: %v", v, err)
+			}
+			return nil
+		},
+	},
+	{
+		Key:  "zerotier_service",
+		Name: "ZeroTier service",
+		Desc: "service restarted after activation (systemd unit, launchd plist or Windows service)",
+		Get:  func(c *ZerotierSwitcherProfile) string { return c.ZerotierService },
+		Default: func(c *ZerotierSwitcherProfile) string {
+			switch runtime.GOOS {
+			case "darwin":
+				return "/Library/LaunchDaemons/com.zerotier.one.plist"
+			case "windows":
+				return "ZeroTier One"
+			default:
+				return "zerotier-one"
+			}
+		},
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			c.ZerotierService = v
+			return nil
+		},
+		Check: func(v string) error {
+			if runtime.GOOS == "darwin" {
+				if _, err := os.Stat(v); err != nil {
+					return fmt.Errorf("launchd plist %s not found", v)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key:     "api_port",
+		Name:    "API port",
+		Desc:    "port of the ZeroTier local API used by zerotier-cli",
+		Get:     func(c *ZerotierSwitcherProfile) string { return formatOptionalInt(c.ZerotierApiPort) },
+		Default: func(c *ZerotierSwitcherProfile) string { return strconv.Itoa(DefaultZerotierApiPort) },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			if v == "" {
+				c.ZerotierApiPort = nil
+				return nil
+			}
+			port, err := strconv.Atoi(v)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("the port must be between 1 and 65535")
+			}
+			c.ZerotierApiPort = &port
+			return nil
+		},
+	},
+	{
+		Key:     "activate_restart",
+		Name:    "Restart on activate",
+		Desc:    "restart the ZeroTier service after replacing the planet file",
+		Choices: []string{"yes", "no"},
+		Get:     func(c *ZerotierSwitcherProfile) string { return skipped(c.Activate.SkipRestart) },
+		Default: func(c *ZerotierSwitcherProfile) string { return "yes" },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			skip, err := parseSkipped(v)
+			if err != nil {
+				return err
+			}
+			c.Activate.SkipRestart = skip
+			return nil
+		},
+	},
+	{
+		Key:     "activate_join",
+		Name:    "Join on activate",
+		Desc:    "join the auto join network of the planet after activation",
+		Choices: []string{"yes", "no"},
+		Get:     func(c *ZerotierSwitcherProfile) string { return skipped(c.Activate.SkipJoin) },
+		Default: func(c *ZerotierSwitcherProfile) string { return "yes" },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			skip, err := parseSkipped(v)
+			if err != nil {
+				return err
+			}
+			c.Activate.SkipJoin = skip
+			return nil
+		},
+	},
+	{
+		Key:     "join_delay",
+		Name:    "Join delay",
+		Desc:    "seconds to wait for the restarted service before joining",
+		Get:     func(c *ZerotierSwitcherProfile) string { return formatOptionalInt(c.Activate.JoinDelay) },
+		Default: func(c *ZerotierSwitcherProfile) string { return strconv.Itoa(DefaultJoinDelay) },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			if v == "" {
+				c.Activate.JoinDelay = nil
+				return nil
+			}
+			delay, err := strconv.Atoi(v)
+			if err != nil || delay < 1 || delay > 300 {
+				return fmt.Errorf("the delay must be between 1 and 300 seconds")
+			}
+			c.Activate.JoinDelay = &delay
+			return nil
+		},
+	},
+	{
+		Key:     "backups",
+		Name:    "Backup retention",
+		Desc:    "number of automatic profile backups kept, -1 to disable",
+		Get:     func(c *ZerotierSwitcherProfile) string { return formatOptionalInt(c.Backups) },
+		Default: func(c *ZerotierSwitcherProfile) string { return strconv.Itoa(DefaultBackupRetention) },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			if v == "" {
+				c.Backups = nil
+				return nil
+			}
+			count, err := strconv.Atoi(v)
+			if err != nil || count < -1 || count == 0 {
+				return fmt.Errorf("the retention must be a positive number, or -1 to disable backups")
+			}
+			c.Backups = &count
+			return nil
+		},
+	},
+	{
+		Key:     "theme",
+		Name:    "Theme",
+		Desc:    "colors of the TUI, auto follows the terminal background",
+		Choices: Themes,
+		Get:     func(c *ZerotierSwitcherProfile) string { return c.Theme },
+		Default: func(c *ZerotierSwitcherProfile) string { return ThemeAuto },
+		Set: func(c *ZerotierSwitcherProfile, v string) error {
+			for _, theme := range append([]string{""}, Themes...) {
+				if v == theme {
+					c.Theme = v
+					return nil
+				}
+			}
+			return fmt.Errorf("unknown theme %s (%s)", v, strings.Join(Themes, ", "))
+		},
+	},
+}
+
+// FindProfileSetting 按名称查找配置项
+func FindProfileSetting(key string) *ProfileSetting {
+	for i := range ProfileSettings {
+		if ProfileSettings[i].Key == key {
+			return &ProfileSettings[i]
+		}
+	}
+	return nil
+}
+
+// ZerotierHome ZeroTier的数据目录
+func (c ZerotierSwitcherProfile) ZerotierHome() string {
+	return c.SettingValue("zerotier_home")
+}
+
+// ZerotierCliCommand zerotier-cli 命令及其参数，自定义数据目录和端口时会传给 zerotier-cli
+func (c ZerotierSwitcherProfile) ZerotierCliCommand(args ...string) (string, []string) {
+	var options []string
+	if folder, _ := GetZerotierProfileFolder(); c.ZerotierHome() != folder {
+		options = append(options, "-D"+c.ZerotierHome())
+	}
+	if port := c.SettingValue("api_port"); port != strconv.Itoa(DefaultZerotierApiPort) {
+		options = append(options, "-p"+port)
+	}
+	return c.SettingValue("zerotier_cli"), append(options, args...)
+}
+
+// ZerotierServiceName 激活后重启的服务
+func (c ZerotierSwitcherProfile) ZerotierServiceName() string {
+	return c.SettingValue("zerotier_service")
+}
+
+// RestartOnActivate 激活后是否重启服务
+func (c ZerotierSwitcherProfile) RestartOnActivate() bool {
+	return c.SettingValue("activate_restart") == "yes"
+}
+
+// JoinOnActivate 激活后是否加入自动加入的网络
+func (c ZerotierSwitcherProfile) JoinOnActivate() bool {
+	return c.SettingValue("activate_join") == "yes"
+}
+
+// JoinDelay 重启服务后加入网络前的等待时间
+func (c ZerotierSwitcherProfile) JoinDelay() time.Duration {
+	delay, err := strconv.Atoi(c.SettingValue("join_delay"))
+	if err != nil || delay <= 0 {
+		delay = DefaultJoinDelay
+	}
+	return time.Duration(delay) * time.Second
+}
+
+// ThemeName 界面配色
+func (c ZerotierSwitcherProfile) ThemeName() string {
+	return c.SettingValue("theme")
+}
+
+// skipped 跳过的步骤显示为 no，执行的步骤为 yes，未设置时为空
+func skipped(skip *bool) string {
+	if skip == nil {
+		return ""
+	}
+	if *skip {
+		return "no"
+	}
+	return "yes"
+}
+
+// parseSkipped 解析 yes/no，返回是否跳过该步骤，空值为未设置
+func parseSkipped(v string) (*bool, error) {
+	var skip bool
+	switch strings.ToLower(v) {
+	case "":
+		return nil, nil
+	case "yes", "y", "true", "on", "1":
+		skip = false
+	case "no", "n", "false", "off", "0":
+		skip = true
+	default:
+		return nil, fmt.Errorf("expected yes or no")
+	}
+	return &skip, nil
+}
+
+func formatOptionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
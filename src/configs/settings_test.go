@@ -0,0 +1,113 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileSettingsEqualToDefault(t *testing.T) {
+	home := defaultHome(t)
+	tests := []struct {
+		key     string
+		profile string // settings stored in the profile, equal to the default
+		system  string
+		want    string
+	}{
+		{key: "zerotier_home", profile: `"zerotier_profile_path":"` + home + `"`, system: "/opt/zerotier", want: home},
+		{key: "api_port", profile: `"zerotier_api_port":9993`, system: "9994", want: "9993"},
+		{key: "activate_restart", profile: `"activate":{"skip_restart":false}`, system: "no", want: "yes"},
+		{key: "activate_join", profile: `"activate":{"skip_join":false}`, system: "no", want: "yes"},
+		{key: "join_delay", profile: `"activate":{"join_delay":3}`, system: "10", want: "3"},
+		{key: "backups", profile: `"backups":20`, system: "-1", want: "20"},
+		{key: "theme", profile: `"theme":"auto"`, system: "dark", want: "auto"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "profile.json")
+			if err := os.WriteFile(path, []byte(`{"version":2,"planets":[],`+tt.profile+`}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := ReadAppConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.systemSettings = settingLayer{
+				values:  map[string]string{tt.key: tt.system},
+				origins: map[string]string{tt.key: "config.json"},
+			}
+			setting := FindProfileSetting(tt.key)
+			if got := setting.Get(cfg); got != tt.want {
+				t.Fatalf("got stored value %q, want %q", got, tt.want)
+			}
+			if source := cfg.EffectiveSetting(tt.key); source.Source != SourceProfile || source.Value != tt.want {
+				t.Fatalf("got %s from %s, want %s from the profile", source.Value, source, tt.want)
+			}
+
+			// the explicit value survives saving the profile
+			if err := cfg.WriteAppConfig(); err != nil {
+				t.Fatal(err)
+			}
+			saved, err := ReadAppConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := setting.Get(saved); got != tt.want {
+				t.Fatalf("got %q after saving", got)
+			}
+
+			// unsetting it falls back to the system config
+			if err := setting.Set(cfg, ""); err != nil {
+				t.Fatal(err)
+			}
+			if source := cfg.EffectiveSetting(tt.key); source.Source != SourceSystem || source.Value != tt.system {
+				t.Fatalf("got %s from %s after unsetting, want %s from the system config", source.Value, source, tt.system)
+			}
+		})
+	}
+}
+
+func TestSettingLayersCheckOnlySyntax(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	path := filepath.Join(t.TempDir(), "profile.json")
+
+	t.Setenv("ZTS_ZEROTIER_HOME", missing)
+	t.Setenv("ZTS_ZEROTIER_CLI", "zerotier-cli-not-installed")
+	cfg, err := ReadAppConfig(path)
+	if err != nil {
+		t.Fatalf("a missing directory or command failed the load: %v", err)
+	}
+	var warnings []string
+	for _, issue := range cfg.SettingWarnings() {
+		if !issue.Warning {
+			t.Fatalf("got error %s", issue)
+		}
+		warnings = append(warnings, issue.Path)
+	}
+	if strings.Join(warnings, ",") != "zerotier_home,zerotier_cli" {
+		t.Fatalf("got warnings %v", cfg.SettingWarnings())
+	}
+
+	t.Setenv("ZTS_API_PORT", "port")
+	if _, err := ReadAppConfig(path); err == nil || !strings.Contains(err.Error(), "ZTS_API_PORT") {
+		t.Fatalf("got error %v for an invalid port", err)
+	}
+}
+
+func TestValidateProfileSettings(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	data := []byte(`{"version":2,"planets":[],"theme":"purple","zerotier_profile_path":"` + missing + `"}`)
+	issues, _ := ValidateProfileData(data, PlanetStoreOf(filepath.Join(t.TempDir(), "profile.json")))
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"error theme: unknown theme purple (auto, dark, light, plain)",
+		"warning zerotier_home: " + missing + " is not a directory, set by profile",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got issues\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
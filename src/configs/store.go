@@ -0,0 +1,294 @@
+package configs
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PlanetStore 按内容(SHA-256)寻址保存的planet文件，位于配置文件所在目录下
+type PlanetStore struct {
+	dir string
+}
+
+// PlanetHash planet文件的内容hash (hex编码的SHA-256)
+func PlanetHash(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func NewPlanetStore(dir string) PlanetStore {
+	return PlanetStore{dir: dir}
+}
+
+// PlanetStoreOf 配置文件使用的planet存储
+func PlanetStoreOf(profilePath string) PlanetStore {
+	return NewPlanetStore(filepath.Join(filepath.Dir(profilePath), "planets"))
+}
+
+// Dir 保存planet文件的目录
+func (s PlanetStore) Dir() string {
+	return s.dir
+}
+
+func (s PlanetStore) path(hash string) string {
+	return filepath.Join(s.dir, hash)
+}
+
+// Put 保存planet文件并返回其hash，已存在时不会重复写入
+func (s PlanetStore) Put(raw []byte) (string, error) {
+	hash := PlanetHash(raw)
+	if _, err := os.Stat(s.path(hash)); err == nil {
+		// a reused planet counts as new, so it is not pruned before the profile is written
+		now := time.Now()
+		return hash, os.Chtimes(s.path(hash), now, now)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+	// write to a temporary file first so a blob is never partially written
+	tmp, err := os.CreateTemp(s.dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	return hash, os.Rename(tmp.Name(), s.path(hash))
+}
+
+// Get 读取planet文件并校验内容hash
+func (s PlanetStore) Get(hash string) ([]byte, error) {
+	raw, err := os.ReadFile(s.path(hash))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("planet %s is missing from the planet store (%s)", shortHash(hash), s.dir)
+	} else if err != nil {
+		return nil, err
+	}
+	if PlanetHash(raw) != hash {
+		return nil, fmt.Errorf("planet %s in the planet store is corrupted", shortHash(hash))
+	}
+	return raw, nil
+}
+
+// load 读取条目的planet数据：旧版本配置中内嵌的数据，或planet存储中的文件；返回旧的hash是否需要迁移
+func (s PlanetStore) load(hash, inlineData *string, data *string) (bool, error) {
+	if *inlineData != "" {
+		raw, err := base64.StdEncoding.DecodeString(*inlineData)
+		if err != nil {
+			return false, fmt.Errorf("planet %s: %v", shortHash(*hash), err)
+		}
+		*data = *inlineData
+		*inlineData = ""
+		*hash = PlanetHash(raw)
+		return true, nil
+	}
+	raw, err := s.Get(*hash)
+	if err != nil {
+		return false, err
+	}
+	*data = base64.StdEncoding.EncodeToString(raw)
+	return false, nil
+}
+
+// save 把条目的planet数据写入planet存储
+func (s PlanetStore) save(hash, data string) error {
+	if data == "" {
+		// could not be loaded, keep the reference as is
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("planet %s: %v", shortHash(hash), err)
+	}
+	stored, err := s.Put(raw)
+	if err != nil {
+		return err
+	}
+	if stored != hash {
+		return fmt.Errorf("planet %s does not match its content hash %s", shortHash(hash), shortHash(stored))
+	}
+	return nil
+}
+
+// loadPlanets 从planet存储读取全部条目的数据，并迁移旧版本配置中内嵌的数据；返回是否有数据被迁移
+func (c *ZerotierSwitcherProfile) loadPlanets() (bool, []error) {
+	migrated := false
+	var errs []error
+	for i := range c.Planets {
+		p := &c.Planets[i]
+		renamed := map[string]string{}
+		oldHash := p.Hash
+		m, err := c.store.load(&p.Hash, &p.InlineData, &p.Data)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		migrated = migrated || m
+		renamed[oldHash] = p.Hash
+		for j := range p.Revisions {
+			rev := &p.Revisions[j]
+			oldHash := rev.Hash
+			m, err := c.store.load(&rev.Hash, &rev.InlineData, &rev.Data)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			migrated = migrated || m
+			renamed[oldHash] = rev.Hash
+		}
+		if newHash, ok := renamed[p.PinnedRevision]; ok {
+			p.PinnedRevision = newHash
+		}
+	}
+	return migrated, errs
+}
+
+// savePlanets 把全部条目的数据写入planet存储
+func (c ZerotierSwitcherProfile) savePlanets() error {
+	for _, p := range c.Planets {
+		if err := c.store.save(p.Hash, p.Data); err != nil {
+			return err
+		}
+		for _, rev := range p.Revisions {
+			if err := c.store.save(rev.Hash, rev.Data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// prunePlanetStore 删除配置和自动备份都不再引用的planet文件，data为刚写入的配置
+func (c ZerotierSwitcherProfile) prunePlanetStore(data []byte) error {
+	keep := map[string]bool{}
+	if err := addPlanetReferences(keep, data); err != nil {
+		return err
+	}
+	backups, err := c.ListBackups(false)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		files = append(files, backup.Path)
+	}
+	// other profiles in the same folder share the planet store and the backups
+	profiles, err := filepath.Glob(filepath.Join(filepath.Dir(c.filePath), "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range profiles {
+		if filepath.Base(path) != filepath.Base(c.filePath) {
+			files = append(files, path)
+		}
+	}
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// keep everything when a file cannot be read, it may reference any planet
+		if err := addPlanetReferences(keep, content); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(path), err)
+		}
+	}
+	_, err = c.store.prune(keep, time.Now().Add(-planetStoreGracePeriod))
+	return err
+}
+
+// addPlanetReferences 收集配置文件引用的planet hash
+func addPlanetReferences(refs map[string]bool, data []byte) error {
+	doc, err := decodeProfileDocument(data)
+	if err != nil {
+		return err
+	}
+	planets, _ := doc["planets"].([]interface{})
+	for _, item := range planets {
+		planet, _ := item.(map[string]interface{})
+		if hash, ok := planet["hash"].(string); ok {
+			refs[hash] = true
+		}
+		revisions, _ := planet["revisions"].([]interface{})
+		for _, revItem := range revisions {
+			rev, _ := revItem.(map[string]interface{})
+			if hash, ok := rev["hash"].(string); ok {
+				refs[hash] = true
+			}
+		}
+	}
+	return nil
+}
+
+// planetStoreGracePeriod 新写入的planet文件在这段时间内不会被删除，其他配置可能正在保存
+const planetStoreGracePeriod = time.Minute
+
+// prune 删除不在keep中、且在before之前写入的planet文件，返回删除的数量
+func (s PlanetStore) prune(keep map[string]bool, before time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || keep[name] || !isPlanetHash(name) {
+			continue
+		}
+		if info, err := entry.Info(); err != nil || info.ModTime().After(before) {
+			continue
+		}
+		if err := os.Remove(s.path(name)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func isPlanetHash(name string) bool {
+	raw, err := hex.DecodeString(name)
+	return err == nil && len(raw) == sha256.Size
+}
+
+// withInlineData 内嵌planet数据的副本，用于导出可以单独使用的备份
+func (c ZerotierSwitcherProfile) withInlineData() ZerotierSwitcherProfile {
+	planets := make([]ZerotierPlanetFile, len(c.Planets))
+	for i, p := range c.Planets {
+		p.InlineData = p.Data
+		revisions := make([]ZerotierPlanetRevision, len(p.Revisions))
+		for j, rev := range p.Revisions {
+			rev.InlineData = rev.Data
+			revisions[j] = rev
+		}
+		if p.Revisions != nil {
+			p.Revisions = revisions
+		}
+		planets[i] = p
+	}
+	c.Planets = planets
+	return c
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
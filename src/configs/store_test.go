@@ -0,0 +1,251 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPlanet(remark, content string) ZerotierPlanetFile {
+	return ZerotierPlanetFile{
+		Hash:   PlanetHash([]byte(content)),
+		Remark: remark,
+		Data:   base64.StdEncoding.EncodeToString([]byte(content)),
+	}
+}
+
+// newTestProfile 在临时目录中创建配置文件
+func newTestProfile(t *testing.T, planets ...ZerotierPlanetFile) *ZerotierSwitcherProfile {
+	t.Helper()
+	cfg, err := ReadAppConfig(filepath.Join(t.TempDir(), "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Planets = planets
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func storedPlanets(t *testing.T, store PlanetStore) []string {
+	t.Helper()
+	entries, err := os.ReadDir(store.Dir())
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// agePlanetStore 把planet文件的修改时间提前到宽限期之前，使其可以被删除
+func agePlanetStore(t *testing.T, store PlanetStore) {
+	t.Helper()
+	old := time.Now().Add(-2 * planetStoreGracePeriod)
+	for _, name := range storedPlanets(t, store) {
+		if err := os.Chtimes(filepath.Join(store.Dir(), name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadProfileFileHasNoSideEffects(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "backup.json")
+	data := []byte(`{"planets":[{"hash":"old","remark":"one","data":"` + base64.StdEncoding.EncodeToString([]byte("planet one")) + `"}]}`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := PlanetStoreOf(filepath.Join(dir, "profile.json"))
+
+	cfg, err := ReadProfileFile(path, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.PlanetErrors()) != 0 || len(cfg.Planets) != 1 || cfg.Planets[0].Hash != PlanetHash([]byte("planet one")) {
+		t.Fatalf("got planets %+v, errors %v", cfg.Planets, cfg.PlanetErrors())
+	}
+	if names := storedPlanets(t, store); len(names) != 0 {
+		t.Fatalf("reading a backup stored planets %v", names)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Fatal("reading a backup changed it")
+	}
+}
+
+func TestMissingPlanetsAreReported(t *testing.T) {
+	cfg := newTestProfile(t, testPlanet("one", "planet one"), testPlanet("two", "planet two"))
+	missing := cfg.Planets[1].Hash
+	if err := os.Remove(filepath.Join(cfg.PlanetStore().Dir(), missing)); err != nil {
+		t.Fatal(err)
+	}
+	corrupted := filepath.Join(cfg.PlanetStore().Dir(), cfg.Planets[0].Hash)
+	if err := os.WriteFile(corrupted, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reread, err := ReadAppConfig(cfg.ConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := reread.PlanetErrors()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "corrupted") || !strings.Contains(errs[1].Error(), "missing") {
+		t.Fatalf("got errors %v", errs)
+	}
+	if len(reread.Planets) != 2 || reread.Planets[1].Hash != missing || reread.Planets[1].Data != "" {
+		t.Fatalf("got planets %+v", reread.Planets)
+	}
+
+	// the entries are kept when the profile is saved, so the data can be put back
+	reread.Theme = ThemeDark
+	if err := reread.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(cfg.ConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), missing) {
+		t.Fatal("the missing planet was dropped from the profile")
+	}
+}
+
+func TestPrunePlanetStore(t *testing.T) {
+	cfg := newTestProfile(t, testPlanet("one", "planet one"), testPlanet("two", "planet two"))
+	one, two := cfg.Planets[0].Hash, cfg.Planets[1].Hash
+	unrelated := filepath.Join(cfg.PlanetStore().Dir(), "README")
+	if err := os.WriteFile(unrelated, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// still referenced by the backup taken before the change
+	cfg.Planets = cfg.Planets[:1]
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(storedPlanets(t, cfg.PlanetStore()), ","); !strings.Contains(names, two) {
+		t.Fatalf("planet referenced by a backup was pruned: %s", names)
+	}
+
+	backups, err := cfg.ListBackups(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backup := range backups {
+		if err := os.Remove(backup.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disabled := -1
+	cfg.Backups = &disabled
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	// planets written moments ago may belong to a profile being saved
+	if names := strings.Join(storedPlanets(t, cfg.PlanetStore()), ","); !strings.Contains(names, two) {
+		t.Fatalf("a new planet was pruned: %s", names)
+	}
+	agePlanetStore(t, cfg.PlanetStore())
+	cfg.Theme = ThemeDark
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	names := storedPlanets(t, cfg.PlanetStore())
+	if strings.Join(names, ",") != strings.Join([]string{"README", one}, ",") {
+		t.Fatalf("got planet store %v, want only %s and README", names, one)
+	}
+}
+
+func TestPrunePlanetStoreSharedFolder(t *testing.T) {
+	disabled := -1
+	first := newTestProfile(t, testPlanet("one", "planet one"), testPlanet("shared", "planet shared"))
+	first.Backups = &disabled
+	second, err := ReadAppConfig(filepath.Join(filepath.Dir(first.ConfigPath()), "work.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Backups = &disabled
+	second.Planets = []ZerotierPlanetFile{testPlanet("two", "planet two"), testPlanet("shared", "planet shared")}
+	if err := second.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if first.PlanetStore().Dir() != second.PlanetStore().Dir() {
+		t.Fatal("profiles in the same folder use different planet stores")
+	}
+	store := first.PlanetStore()
+	one, two, shared := PlanetHash([]byte("planet one")), PlanetHash([]byte("planet two")), PlanetHash([]byte("planet shared"))
+
+	// removing the shared planet from one profile keeps it for the other
+	agePlanetStore(t, store)
+	first.Planets = first.Planets[:1]
+	if err := first.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(storedPlanets(t, store), ","); !strings.Contains(names, two) || !strings.Contains(names, shared) {
+		t.Fatalf("planets of the other profile were pruned: %s", names)
+	}
+
+	// nothing is pruned while another profile in the folder cannot be read
+	broken := filepath.Join(filepath.Dir(first.ConfigPath()), "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second.Planets = second.Planets[:1]
+	if err := second.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if names := storedPlanets(t, store); len(names) != 3 {
+		t.Fatalf("got planet store %v with an unreadable profile", names)
+	}
+
+	if err := os.Remove(broken); err != nil {
+		t.Fatal(err)
+	}
+	second.Theme = ThemeDark
+	if err := second.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{one, two}
+	sort.Strings(want)
+	names := storedPlanets(t, store)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("got planet store %v, want %s and %s", names, one, two)
+	}
+}
+
+func TestRestoreBackupWithMissingPlanets(t *testing.T) {
+	cfg := newTestProfile(t, testPlanet("one", "planet one"))
+	cfg.Planets = []ZerotierPlanetFile{testPlanet("gone", "planet gone")}
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Planets = []ZerotierPlanetFile{testPlanet("one", "planet one")}
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(cfg.PlanetStore().Dir(), PlanetHash([]byte("planet gone")))); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := cfg.ListBackups(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(backups[0].Describe(), "1 unreadable") {
+		t.Fatalf("got description %q", backups[0].Describe())
+	}
+	if err := cfg.RestoreBackup(backups[0]); err == nil || !strings.Contains(err.Error(), "cannot be restored") {
+		t.Fatalf("got error %v", err)
+	}
+	if len(cfg.Planets) != 1 || cfg.Planets[0].Remark != "one" {
+		t.Fatalf("profile changed by a failed restore: %+v", cfg.Planets)
+	}
+}
@@ -1,22 +1,18 @@
 package tools
 
 import (
-	"crypto/md5"
 	"encoding/base64"
-	"encoding/hex"
 	"fmt"
 	"github.com/LanceLRQ/zerotier-switcher/src/configs"
 	"os"
 	"os/exec"
-	"path"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 )
 
-// ReplacePlanetAndJoinNetwork 替换 planet 文件并加入指定网络
-func ReplacePlanetAndJoinNetwork(base64Planet string, networkID string, callback func(int, string)) error {
+// ReplacePlanetAndJoinNetwork 替换 planet 文件并加入指定网络，按配置中的激活选项重启服务
+func ReplacePlanetAndJoinNetwork(cfg *configs.ZerotierSwitcherProfile, base64Planet string, networkID string, callback func(int, string)) error {
 	// 1. 解码 base64 planet 数据
 	callback(1, "Decoding planet")
 	planetData, err := base64.StdEncoding.DecodeString(base64Planet)
@@ -26,17 +22,11 @@ func ReplacePlanetAndJoinNetwork(base64Planet string, networkID string, callback
 
 	// 2. 获取 planet 文件路径
 	callback(2, "Get planet path")
-	planetPathFolder, err := configs.GetZerotierProfileFolder()
-	if err != nil {
-		return fmt.Errorf("get planet file path error: %v", err)
-	}
-
-	planetPath := path.Join(planetPathFolder, "planet")
+	planetPath := configs.GetPlanetFilePath(cfg)
 
 	// 3. 检查是否已是当前planet
 	callback(3, "Checking planet file")
-	newHash := md5.Sum(planetData)
-	newHashStr := hex.EncodeToString(newHash[:])
+	newHashStr := configs.PlanetHash(planetData)
 	existingHashStr, err := getFileHash(planetPath)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("check planet file error: %v", err)
@@ -52,15 +42,19 @@ func ReplacePlanetAndJoinNetwork(base64Planet string, networkID string, callback
 	}
 
 	// 5. 重启 ZeroTier 服务
+	if !cfg.RestartOnActivate() {
+		callback(7, "Done, restart the zerotier service to use the new planet")
+		return nil
+	}
 	callback(5, "Restarting zerotier service, please wait")
-	if err := restartZeroTierService(); err != nil {
+	if err := restartZeroTierService(cfg); err != nil {
 		return fmt.Errorf("restart zerotier service error: %v", err)
 	}
 
-	if networkID != "" {
+	if networkID != "" && cfg.JoinOnActivate() {
 		callback(6, "Restarting zerotier service, please wait")
 		// 6. 加入指定网络
-		if err := joinZeroTierNetwork(networkID); err != nil {
+		if err := joinZeroTierNetwork(cfg, networkID); err != nil {
 			return fmt.Errorf("join network error: %v", err)
 		}
 		callback(7, "Done")
@@ -71,29 +65,29 @@ func ReplacePlanetAndJoinNetwork(base64Planet string, networkID string, callback
 	return nil
 }
 
-// getFileHash 计算文件的 MD5 哈希
+// getFileHash 计算文件的内容hash，与planet存储使用的hash相同
 func getFileHash(filePath string) (string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", err
 	}
-	hash := md5.Sum(data)
-	return hex.EncodeToString(hash[:]), nil
+	return configs.PlanetHash(data), nil
 }
 
 // restartZeroTierService 重启 ZeroTier 服务
-func restartZeroTierService() error {
+func restartZeroTierService(cfg *configs.ZerotierSwitcherProfile) error {
+	service := cfg.ZerotierServiceName()
 	switch runtime.GOOS {
 	case "linux":
 		// 尝试 systemd
 		if _, err := exec.LookPath("systemctl"); err == nil {
-			if err := exec.Command("systemctl", "restart", "zerotier-one").Run(); err == nil {
+			if err := exec.Command("systemctl", "restart", service).Run(); err == nil {
 				return nil
 			}
 		}
 		// 尝试 service 命令
 		if _, err := exec.LookPath("service"); err == nil {
-			if err := exec.Command("service", "zerotier-one", "restart").Run(); err == nil {
+			if err := exec.Command("service", service, "restart").Run(); err == nil {
 				return nil
 			}
 		}
@@ -103,15 +97,15 @@ func restartZeroTierService() error {
 
 	case "darwin":
 		// macOS
-		exec.Command("launchctl", "unload", "/Library/LaunchDaemons/com.zerotier.one.plist").Run()
-		exec.Command("launchctl", "load", "/Library/LaunchDaemons/com.zerotier.one.plist").Run()
+		exec.Command("launchctl", "unload", service).Run()
+		exec.Command("launchctl", "load", service).Run()
 		return nil
 
 	case "windows":
 		// Windows
-		exec.Command("net", "stop", "ZeroTier One").Run()
+		exec.Command("net", "stop", service).Run()
 		time.Sleep(2 * time.Second)
-		return exec.Command("net", "start", "ZeroTier One").Run()
+		return exec.Command("net", "start", service).Run()
 
 	default:
 		return fmt.Errorf("unsupport operation system: %s", runtime.GOOS)
@@ -119,7 +113,7 @@ func restartZeroTierService() error {
 }
 
 // joinZeroTierNetwork 加入 ZeroTier 网络
-func joinZeroTierNetwork(networkID string) error {
+func joinZeroTierNetwork(cfg *configs.ZerotierSwitcherProfile, networkID string) error {
 	// 清理网络ID，移除可能的前后空格和非字母数字字符
 	cleanID := strings.TrimSpace(networkID)
 	if len(cleanID) != 16 {
@@ -127,15 +121,10 @@ func joinZeroTierNetwork(networkID string) error {
 	}
 
 	// 等待服务完全启动
-	time.Sleep(3 * time.Second)
+	time.Sleep(cfg.JoinDelay())
 
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.Command(filepath.Join(os.Getenv("ProgramFiles"), "ZeroTier", "One", "zerotier-cli.bat"), "join", cleanID)
-	default:
-		cmd = exec.Command("zerotier-cli", "join", cleanID)
-	}
+	name, args := cfg.ZerotierCliCommand("join", cleanID)
+	cmd := exec.Command(name, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -145,14 +134,9 @@ func joinZeroTierNetwork(networkID string) error {
 	return nil
 }
 
-func GetCurrentPlanetHashFromOS() string {
-	planetPathFolder, err := configs.GetZerotierProfileFolder()
-	if err != nil {
-		return ""
-	}
-
-	planetPath := path.Join(planetPathFolder, "planet")
-	existingHashStr, err := getFileHash(planetPath)
+// GetCurrentPlanetHashFromOS ZeroTier当前使用的planet文件的hash
+func GetCurrentPlanetHashFromOS(cfg *configs.ZerotierSwitcherProfile) string {
+	existingHashStr, err := getFileHash(configs.GetPlanetFilePath(cfg))
 	if err != nil && !os.IsNotExist(err) {
 		return ""
 	}
@@ -164,7 +148,6 @@ func CheckIsCurrentPlanet(base64Planet, existingHashStr string) bool {
 	if err != nil {
 		return false
 	}
-	newHash := md5.Sum(planetData)
-	newHashStr := hex.EncodeToString(newHash[:])
+	newHashStr := configs.PlanetHash(planetData)
 	return existingHashStr != "" && newHashStr != "" && existingHashStr == newHashStr
 }
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"fmt"
+	"os"
+)
+
+// C25519 public keys are a 32 byte Curve25519 key followed by a 32 byte Ed25519 key.
+// Signatures are an Ed25519 signature of the first 32 bytes of SHA-512(message),
+// followed by those 32 bytes.
+
+// C25519Verify 校验ZeroTier的C25519签名
+func C25519Verify(publicKey [ZT_C25519_PUBLIC_KEY_LEN]byte, message []byte, signature [ZT_C25519_SIGNATURE_LEN]byte) bool {
+	digest := sha512.Sum512(message)
+	if !bytes.Equal(signature[64:96], digest[:32]) {
+		return false
+	}
+	return ed25519.Verify(publicKey[32:], digest[:32], signature[:64])
+}
+
+// C25519Sign 使用C25519私钥签名，私钥的后32字节为Ed25519种子
+func C25519Sign(privateKey [ZT_C25519_PRIVATE_KEY_LEN]byte, message []byte) [ZT_C25519_SIGNATURE_LEN]byte {
+	var signature [ZT_C25519_SIGNATURE_LEN]byte
+	digest := sha512.Sum512(message)
+	copy(signature[:64], ed25519.Sign(ed25519.NewKeyFromSeed(privateKey[32:]), digest[:32]))
+	copy(signature[64:], digest[:32])
+	return signature
+}
+
+// C25519KeyPair C25519密钥对，二进制格式与mkworld的 current.c25519 相同(公钥在前，私钥在后)
+type C25519KeyPair struct {
+	Public  [ZT_C25519_PUBLIC_KEY_LEN]byte
+	Private [ZT_C25519_PRIVATE_KEY_LEN]byte
+}
+
+// ParseC25519KeyPair 解析 current.c25519 格式的密钥对，并检查公钥与私钥是否对应
+func ParseC25519KeyPair(data []byte) (*C25519KeyPair, error) {
+	if len(data) != ZT_C25519_PUBLIC_KEY_LEN+ZT_C25519_PRIVATE_KEY_LEN {
+		return nil, fmt.Errorf("invalid key pair length %d (expected %d)", len(data), ZT_C25519_PUBLIC_KEY_LEN+ZT_C25519_PRIVATE_KEY_LEN)
+	}
+	pair := &C25519KeyPair{}
+	copy(pair.Public[:], data[:ZT_C25519_PUBLIC_KEY_LEN])
+	copy(pair.Private[:], data[ZT_C25519_PUBLIC_KEY_LEN:])
+	signingKey := ed25519.NewKeyFromSeed(pair.Private[32:])
+	if !bytes.Equal(signingKey.Public().(ed25519.PublicKey), pair.Public[32:]) {
+		return nil, fmt.Errorf("public key does not match the private key")
+	}
+	return pair, nil
+}
+
+// ReadC25519KeyPair 读取 current.c25519 / previous.c25519 文件
+func ReadC25519KeyPair(filename string) (*C25519KeyPair, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read file (%s) error: %v", filename, err)
+	}
+	return ParseC25519KeyPair(content)
+}
+
+// Bytes 密钥对的二进制格式
+func (p *C25519KeyPair) Bytes() []byte {
+	return append(append([]byte{}, p.Public[:]...), p.Private[:]...)
+}
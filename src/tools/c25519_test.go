@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestC25519SignVerify(t *testing.T) {
+	pair, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("zerotier world update")
+	signature := C25519Sign(pair.Private, message)
+
+	if !C25519Verify(pair.Public, message, signature) {
+		t.Fatal("signature does not verify with the signing key")
+	}
+	if again := C25519Sign(pair.Private, message); again != signature {
+		t.Fatal("signatures of the same message differ")
+	}
+	if C25519Verify(other.Public, message, signature) {
+		t.Fatal("signature verifies with another key")
+	}
+	if C25519Verify(pair.Public, []byte("zerotier world updatE"), signature) {
+		t.Fatal("signature verifies a modified message")
+	}
+	for _, i := range []int{0, 63, 64, 95} {
+		tampered := signature
+		tampered[i] ^= 0x01
+		if C25519Verify(pair.Public, message, tampered) {
+			t.Fatalf("signature verifies with byte %d modified", i)
+		}
+	}
+}
+
+func TestC25519KeyPairLayout(t *testing.T) {
+	pair, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first half of each key is the Curve25519 agreement key
+	x, err := ecdh.X25519().NewPrivateKey(pair.Private[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(x.PublicKey().Bytes(), pair.Public[:32]) {
+		t.Fatal("Curve25519 public key does not match the private key")
+	}
+
+	parsed, err := ParseC25519KeyPair(pair.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != *pair {
+		t.Fatal("key pair changed after a round trip")
+	}
+	data := pair.Bytes()
+	data[ZT_C25519_PUBLIC_KEY_LEN-1] ^= 0x01
+	if _, err := ParseC25519KeyPair(data); err == nil {
+		t.Fatal("mismatched key pair was accepted")
+	}
+	if _, err := ParseC25519KeyPair(data[1:]); err == nil {
+		t.Fatal("truncated key pair was accepted")
+	}
+}
+
+func TestWorldSignatureMessage(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "worlds", "two-roots.planet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := ParseWorld(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(world.Serialize(false), data) {
+		t.Fatal("serialized world differs from the file")
+	}
+
+	// 0x7f*8, the world without its signature, 0xf7*8
+	const headerLen = 1 + 8 + 8 + ZT_C25519_PUBLIC_KEY_LEN
+	message := world.Serialize(true)
+	want := append(bytes.Repeat([]byte{0x7f}, 8), data[:headerLen]...)
+	want = append(want, data[headerLen+ZT_C25519_SIGNATURE_LEN:]...)
+	want = append(want, bytes.Repeat([]byte{0xf7}, 8)...)
+	if !bytes.Equal(message, want) {
+		t.Fatalf("signing message:\n got %x\nwant %x", message, want)
+	}
+}
+
+func TestWorldVerifySignature(t *testing.T) {
+	for _, file := range worldCorpus(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			world, err := ParseWorld(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !world.VerifySignature(world.UpdatesMustBeSignedBy) {
+				t.Fatal("signature does not verify")
+			}
+			tampered := *world
+			tampered.Timestamp++
+			if tampered.VerifySignature(world.UpdatesMustBeSignedBy) {
+				t.Fatal("signature verifies a modified timestamp")
+			}
+		})
+	}
+}
+
+func TestWorldIsUpdateOf(t *testing.T) {
+	key, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "worlds", "single-root.planet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ParseWorld(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// take over the world with a key we hold
+	old.UpdatesMustBeSignedBy = key.Public
+	if err := old.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	update, err := ParseWorld(old.Serialize(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.BumpTimestamp()
+	if err := update.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	if !update.IsUpdateOf(old) {
+		t.Fatal("signed newer revision is not an update")
+	}
+	if old.IsUpdateOf(update) {
+		t.Fatal("older revision is an update")
+	}
+
+	other, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := update.Sign(other); err == nil {
+		t.Fatal("signed with a key that is not the update signing key")
+	}
+	update.Signature = C25519Sign(other.Private, update.Serialize(true))
+	if update.IsUpdateOf(old) {
+		t.Fatal("revision signed by another key is an update")
+	}
+}
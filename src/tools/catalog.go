@@ -0,0 +1,366 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const CatalogIndexFileName = "catalog.json"
+
+// CatalogDocument 目录文件，Index 为签名内容
+type CatalogDocument struct {
+	Index     json.RawMessage `json:"index"`
+	Signature string          `json:"signature"` // hex encoded ed25519 signature of the compacted index
+}
+
+type CatalogIndex struct {
+	Name      string         `json:"name"`
+	Generated int64          `json:"generated"`
+	Planets   []CatalogEntry `json:"planets"`
+}
+
+type CatalogEntry struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	Networks []string `json:"networks,omitempty"`
+	Hash     string   `json:"hash"`           // sha256 of the raw planet file
+	Data     string   `json:"data,omitempty"` // base64 encoded planet file
+	File     string   `json:"file,omitempty"` // planet file relative to the catalog location
+}
+
+// CatalogSnapshot 拉取并校验后的目录内容
+type CatalogSnapshot struct {
+	Index       CatalogIndex
+	Planets     map[string]*World // keyed by CatalogEntry.Id
+	ETag        string
+	NotModified bool
+}
+
+type CatalogSyncResult struct {
+	Added     int
+	Updated   int
+	Retired   int
+	Removed   int
+	Unchanged int
+}
+
+func (r CatalogSyncResult) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d retired, %d removed, %d unchanged",
+		r.Added, r.Updated, r.Retired, r.Removed, r.Unchanged)
+}
+
+var catalogHttpClient = &http.Client{Timeout: 30 * time.Second}
+
+const (
+	maxCatalogSize       = 8 << 20  // catalog.json downloaded from a remote catalog
+	maxCatalogPlanetSize = 64 << 10 // planet or moon file referenced by a catalog entry
+)
+
+// GenerateCatalogKey 生成目录签名用的团队密钥
+func GenerateCatalogKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+// ReadCatalogKey 读取hex编码的团队私钥文件
+func ReadCatalogKey(keyFile string) (ed25519.PrivateKey, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read key file (%s) error: %v", keyFile, err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("decode key file error: %v", err)
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+}
+
+// ParseCatalogPublicKey 解析hex编码的团队公钥
+func ParseCatalogPublicKey(text string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key error: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length %d", len(key))
+	}
+	return key, nil
+}
+
+// SignCatalog 签名目录索引
+func SignCatalog(index CatalogIndex, key ed25519.PrivateKey) (*CatalogDocument, error) {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogDocument{
+		Index:     data,
+		Signature: hex.EncodeToString(ed25519.Sign(key, data)),
+	}, nil
+}
+
+// Verify 校验目录签名并解析索引
+func (d CatalogDocument) Verify(key ed25519.PublicKey) (*CatalogIndex, error) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, d.Index); err != nil {
+		return nil, fmt.Errorf("malformed catalog index: %v", err)
+	}
+	sig, err := hex.DecodeString(d.Signature)
+	if err != nil || !ed25519.Verify(key, compacted.Bytes(), sig) {
+		return nil, fmt.Errorf("catalog signature mismatch")
+	}
+	index := &CatalogIndex{}
+	if err := json.Unmarshal(compacted.Bytes(), index); err != nil {
+		return nil, fmt.Errorf("malformed catalog index: %v", err)
+	}
+	return index, nil
+}
+
+func isRemoteCatalog(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// readCatalogSource 读取目录内容，本地目录以内容hash作为ETag
+func readCatalogSource(source, etag string) ([]byte, string, bool, error) {
+	if !isRemoteCatalog(source) {
+		indexPath := source
+		if s, err := os.Stat(source); err == nil && s.IsDir() {
+			indexPath = filepath.Join(source, CatalogIndexFileName)
+		}
+		data, err := os.ReadFile(indexPath)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("read catalog error: %v", err)
+		}
+		sum := sha256.Sum256(data)
+		newTag := hex.EncodeToString(sum[:])
+		return data, newTag, etag != "" && etag == newTag, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := catalogHttpClient.Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("fetch catalog error: %v", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, true, nil
+	case http.StatusOK:
+	default:
+		return nil, "", false, fmt.Errorf("fetch catalog error: %s", resp.Status)
+	}
+	data, err := readLimited(resp.Body, maxCatalogSize)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("fetch catalog error: %v", err)
+	}
+	return data, resp.Header.Get("ETag"), false, nil
+}
+
+// readCatalogPlanet 读取目录条目对应的planet文件
+func readCatalogPlanet(source string, entry CatalogEntry) ([]byte, error) {
+	if entry.Data != "" {
+		return base64.StdEncoding.DecodeString(entry.Data)
+	}
+	if entry.File == "" {
+		return nil, fmt.Errorf("no planet data")
+	}
+	if !isRemoteCatalog(source) {
+		dir := source
+		if s, err := os.Stat(source); err == nil && !s.IsDir() {
+			dir = filepath.Dir(source)
+		}
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(entry.File)))
+	}
+	base, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(entry.File)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := catalogHttpClient.Get(base.ResolveReference(ref).String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download error: %s", resp.Status)
+	}
+	return readLimited(resp.Body, maxCatalogPlanetSize)
+}
+
+// readLimited 读取至多limit字节，超出时返回错误而不是截断
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response is larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// FetchCatalog 拉取目录，校验签名以及每个planet的hash
+func FetchCatalog(catalog configs.ZerotierCatalog) (*CatalogSnapshot, error) {
+	key, err := ParseCatalogPublicKey(catalog.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	data, etag, notModified, err := readCatalogSource(catalog.Source, catalog.ETag)
+	if err != nil {
+		return nil, err
+	}
+	if notModified {
+		return &CatalogSnapshot{ETag: etag, NotModified: true}, nil
+	}
+
+	doc := CatalogDocument{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("malformed catalog: %v", err)
+	}
+	index, err := doc.Verify(key)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &CatalogSnapshot{
+		Index:   *index,
+		Planets: map[string]*World{},
+		ETag:    etag,
+	}
+	for _, entry := range index.Planets {
+		if entry.Id == "" {
+			return nil, fmt.Errorf("catalog entry without id")
+		}
+		if _, ok := snapshot.Planets[entry.Id]; ok {
+			return nil, fmt.Errorf("duplicated catalog entry (%s)", entry.Id)
+		}
+		raw, err := readCatalogPlanet(catalog.Source, entry)
+		if err != nil {
+			return nil, fmt.Errorf("catalog entry (%s): %v", entry.Id, err)
+		}
+		sum := sha256.Sum256(raw)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), entry.Hash) {
+			return nil, fmt.Errorf("catalog entry (%s): hash mismatch", entry.Id)
+		}
+		world, err := ParseWorld(raw)
+		if err != nil {
+			return nil, fmt.Errorf("catalog entry (%s): %v", entry.Id, err)
+		}
+		snapshot.Planets[entry.Id] = world
+	}
+	return snapshot, nil
+}
+
+// ApplyCatalog 将目录内容同步到配置中：新增、更新，以及下架已移除的条目
+func ApplyCatalog(cfg *configs.ZerotierSwitcherProfile, name string, snapshot *CatalogSnapshot) CatalogSyncResult {
+	result := CatalogSyncResult{}
+	catalog := cfg.FindCatalog(name)
+	if catalog == nil {
+		return result
+	}
+	catalog.LastSync = time.Now().Unix()
+	catalog.ETag = snapshot.ETag
+	if snapshot.NotModified {
+		for _, p := range cfg.Planets {
+			if p.Catalog == name {
+				result.Unchanged++
+			}
+		}
+		return result
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range snapshot.Index.Planets {
+		seen[entry.Id] = true
+		world := snapshot.Planets[entry.Id]
+		planet := MakePlanetFile(world, entry.Name)
+		planet.Catalog = name
+		planet.CatalogEntry = entry.Id
+		planet.Networks = entry.Networks
+		if len(entry.Networks) > 0 {
+			planet.AutoJoinNetwork = entry.Networks[0]
+		}
+
+		idx := -1
+		for i, p := range cfg.Planets {
+			if p.Catalog == name && p.CatalogEntry == entry.Id {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			// 已手动导入的同一个planet，交给目录管理
+			for i, p := range cfg.Planets {
+				if p.Catalog == "" && p.Hash == planet.Hash {
+					idx = i
+					if planet.AutoJoinNetwork == "" {
+						planet.AutoJoinNetwork = p.AutoJoinNetwork
+					}
+					break
+				}
+			}
+		}
+		if idx < 0 {
+			cfg.Planets = append(cfg.Planets, planet)
+			result.Added++
+			continue
+		}
+		old := cfg.Planets[idx]
+		if old.Data == planet.Data && old.Remark == planet.Remark && old.Catalog == planet.Catalog &&
+			!old.Retired && strings.Join(old.Networks, ",") == strings.Join(planet.Networks, ",") {
+			result.Unchanged++
+			continue
+		}
+		cfg.Planets[idx] = planet
+		result.Updated++
+	}
+
+	cHash := GetCurrentPlanetHashFromOS(cfg)
+	var planets []configs.ZerotierPlanetFile
+	for _, p := range cfg.Planets {
+		if p.Catalog == name && !seen[p.CatalogEntry] {
+			// 正在使用的planet保留并标记为下架
+			if CheckIsCurrentPlanet(p.Data, cHash) {
+				if !p.Retired {
+					p.Retired = true
+					result.Retired++
+				}
+			} else {
+				result.Removed++
+				continue
+			}
+		}
+		planets = append(planets, p)
+	}
+	cfg.Planets = planets
+	return result
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CatalogServer 将配置中的planet发布为签名目录
+type CatalogServer struct {
+	Name       string
+	Tag        string // only publish planets with this tag, empty for all
+	Key        ed25519.PrivateKey
+	ConfigPath string
+}
+
+// BuildCatalogIndex 生成目录索引，返回索引以及条目id对应的planet数据
+func BuildCatalogIndex(cfg *configs.ZerotierSwitcherProfile, name, tag string) (CatalogIndex, map[string][]byte, error) {
+	index := CatalogIndex{
+		Name:      name,
+		Generated: time.Now().Unix(),
+		Planets:   []CatalogEntry{},
+	}
+	files := map[string][]byte{}
+	for _, p := range cfg.Planets {
+		if tag != "" && !p.HasTag(tag) {
+			continue
+		}
+		if _, ok := files[p.Hash]; ok {
+			continue
+		}
+		if p.Data == "" {
+			return index, nil, fmt.Errorf("planet (%s): data is missing from the planet store", p.Remark)
+		}
+		raw, err := base64.StdEncoding.DecodeString(p.Data)
+		if err != nil {
+			return index, nil, fmt.Errorf("planet (%s): %v", p.Remark, err)
+		}
+		networks := p.Networks
+		if len(networks) == 0 && p.AutoJoinNetwork != "" {
+			networks = []string{p.AutoJoinNetwork}
+		}
+		ext := "planet"
+		if p.WorldType == ZT_WORLD_TYPE_MOON {
+			ext = "moon"
+		}
+		sum := sha256.Sum256(raw)
+		index.Planets = append(index.Planets, CatalogEntry{
+			Id:       p.Hash,
+			Name:     p.Remark,
+			Networks: networks,
+			Hash:     hex.EncodeToString(sum[:]),
+			File:     fmt.Sprintf("planets/%s.%s", p.Hash, ext),
+		})
+		files[p.Hash] = raw
+	}
+	return index, files, nil
+}
+
+func (s *CatalogServer) load() (*CatalogIndex, map[string][]byte, error) {
+	cfg, err := configs.ReadAppConfig(s.ConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, files, err := BuildCatalogIndex(cfg, s.Name, s.Tag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &index, files, nil
+}
+
+func (s *CatalogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	index, files, err := s.load()
+	if err != nil {
+		log.Printf("serve: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/" || r.URL.Path == "/"+CatalogIndexFileName:
+		// 仅用内容部分计算ETag，避免生成时间导致缓存失效
+		index.Generated = 0
+		content, _ := json.Marshal(index.Planets)
+		sum := sha256.Sum256(content)
+		etag := fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16]))
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		index.Generated = time.Now().Unix()
+		doc, err := SignCatalog(*index, s.Key)
+		if err != nil {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(doc)
+	case strings.HasPrefix(r.URL.Path, "/planets/"):
+		fileName := strings.TrimPrefix(r.URL.Path, "/planets/")
+		for _, entry := range index.Planets {
+			if entry.File != "planets/"+fileName {
+				continue
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName(entry, files)))
+			_, _ = w.Write(files[entry.Id])
+			return
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func downloadName(entry CatalogEntry, files map[string][]byte) string {
+	world, err := ParseWorld(files[entry.Id])
+	if err != nil || world.Type != ZT_WORLD_TYPE_MOON {
+		return "planet"
+	}
+	return fmt.Sprintf("%016x.moon", world.ID)
+}
@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readTestWorld 读取 testdata/worlds 中的文件
+func readTestWorld(t *testing.T, name string) (*World, []byte) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "worlds", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := ParseWorld(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return world, raw
+}
+
+// newTestProfile 在临时目录中创建保存了指定planet的配置
+func newTestProfile(t *testing.T, names ...string) *configs.ZerotierSwitcherProfile {
+	t.Helper()
+	dir := t.TempDir()
+	cfg, err := configs.ReadAppConfig(filepath.Join(dir, "profile.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keep the planet of the test machine out of the tests
+	cfg.ZerotierProfilePath = filepath.Join(dir, "zerotier")
+	for _, name := range names {
+		world, _ := readTestWorld(t, name)
+		if _, _, err := StoreWorld(cfg, world, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestBuildCatalogIndexTagFilter(t *testing.T) {
+	cfg := newTestProfile(t, "single-root.planet", "two-roots.planet", "0000007c69592601.moon")
+	cfg.Planets[0].Tags = []string{"team"}
+	cfg.Planets[2].Tags = []string{"team", "lab"}
+	cfg.Planets[2].AutoJoinNetwork = "8056c2e21c000001"
+
+	for _, tt := range []struct {
+		tag  string
+		want []string
+	}{
+		{tag: "", want: []string{"single-root.planet", "two-roots.planet", "0000007c69592601.moon"}},
+		{tag: "team", want: []string{"single-root.planet", "0000007c69592601.moon"}},
+		{tag: "lab", want: []string{"0000007c69592601.moon"}},
+		{tag: "other"},
+	} {
+		index, files, err := BuildCatalogIndex(cfg, "team", tt.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range index.Planets {
+			names = append(names, entry.Name)
+			if files[entry.Id] == nil {
+				t.Fatalf("tag %q: no file for %s", tt.tag, entry.Name)
+			}
+		}
+		if len(names) != len(tt.want) {
+			t.Fatalf("tag %q: got %v, want %v", tt.tag, names, tt.want)
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Fatalf("tag %q: got %v, want %v", tt.tag, names, tt.want)
+			}
+		}
+	}
+
+	index, _, _ := BuildCatalogIndex(cfg, "team", "lab")
+	moon := index.Planets[0]
+	if moon.File != "planets/"+cfg.Planets[2].Hash+".moon" || len(moon.Networks) != 1 || moon.Networks[0] != "8056c2e21c000001" {
+		t.Fatalf("got moon entry %+v", moon)
+	}
+}
+
+// startCatalogServer 在测试服务器上发布配置中的planet
+func startCatalogServer(t *testing.T, cfg *configs.ZerotierSwitcherProfile, tag string) (*httptest.Server, ed25519.PublicKey) {
+	t.Helper()
+	pub, key, err := GenerateCatalogKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&CatalogServer{Name: "team", Tag: tag, Key: key, ConfigPath: cfg.ConfigPath()})
+	t.Cleanup(server.Close)
+	return server, pub
+}
+
+func get(t *testing.T, url, etag string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestCatalogServerIndex(t *testing.T) {
+	cfg := newTestProfile(t, "single-root.planet", "two-roots.planet")
+	cfg.Planets[1].Tags = []string{"team"}
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	server, pub := startCatalogServer(t, cfg, "team")
+
+	resp, body := get(t, server.URL+"/"+CatalogIndexFileName, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	var doc CatalogDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	index, err := doc.Verify(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Name != "team" || len(index.Planets) != 1 || index.Planets[0].Id != cfg.Planets[1].Hash {
+		t.Fatalf("got index %+v", index)
+	}
+	other, _, _ := GenerateCatalogKey()
+	if _, err := doc.Verify(other); err == nil {
+		t.Fatal("the index verifies with another key")
+	}
+
+	// the ETag only depends on the entries, not on the generation time
+	etag := resp.Header.Get("ETag")
+	if resp, _ := get(t, server.URL+"/", etag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("got status %d with a matching ETag", resp.StatusCode)
+	}
+	cfg.Planets[0].Tags = []string{"team"}
+	if err := cfg.WriteAppConfig(); err != nil {
+		t.Fatal(err)
+	}
+	resp, _ = get(t, server.URL+"/", etag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Fatalf("got status %d, ETag %s after the entries changed", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// the published catalog can be subscribed to
+	snapshot, err := FetchCatalog(configs.ZerotierCatalog{Name: "team", Source: server.URL + "/" + CatalogIndexFileName, PublicKey: hex.EncodeToString(pub)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Planets) != 2 {
+		t.Fatalf("got %d planets", len(snapshot.Planets))
+	}
+}
+
+func TestCatalogServerFiles(t *testing.T) {
+	cfg := newTestProfile(t, "single-root.planet", "0000007c69592601.moon")
+	server, _ := startCatalogServer(t, cfg, "")
+	_, moonRaw := readTestWorld(t, "0000007c69592601.moon")
+	_, planetRaw := readTestWorld(t, "single-root.planet")
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   []byte
+		wantName   string
+	}{
+		{path: "/planets/" + cfg.Planets[1].Hash + ".moon", wantStatus: http.StatusOK, wantBody: moonRaw, wantName: `attachment; filename="0000007c69592601.moon"`},
+		{path: "/planets/" + cfg.Planets[0].Hash + ".planet", wantStatus: http.StatusOK, wantBody: planetRaw, wantName: `attachment; filename="planet"`},
+		{path: "/planets/" + cfg.Planets[1].Hash + ".planet", wantStatus: http.StatusNotFound},
+		{path: "/planets/unknown.planet", wantStatus: http.StatusNotFound},
+		{path: "/profile.json", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, body := get(t, server.URL+tt.path, "")
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if string(body) != string(tt.wantBody) {
+				t.Fatal("the served file differs from the stored planet")
+			}
+			if got := resp.Header.Get("Content-Disposition"); got != tt.wantName {
+				t.Fatalf("got Content-Disposition %s", got)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("got status %d for POST", resp.StatusCode)
+	}
+}
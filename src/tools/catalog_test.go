@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCatalog 提供签名目录的测试服务器
+type testCatalog struct {
+	key         ed25519.PrivateKey
+	index       CatalogIndex
+	files       map[string][]byte
+	etag        string
+	requests    int
+	ifNoneMatch string
+	tamper      func(doc *CatalogDocument, key ed25519.PrivateKey)
+}
+
+func newTestCatalog(t *testing.T, names ...string) *testCatalog {
+	t.Helper()
+	_, key, err := GenerateCatalogKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testCatalog{key: key, files: map[string][]byte{}, etag: `"v1"`}
+	c.index = CatalogIndex{Name: "team", Generated: 1}
+	for _, name := range names {
+		c.addPlanet(t, name)
+	}
+	return c
+}
+
+func (c *testCatalog) addPlanet(t *testing.T, name string) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "worlds", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(raw)
+	c.index.Planets = append(c.index.Planets, CatalogEntry{
+		Id:   name,
+		Name: name,
+		Hash: hex.EncodeToString(sum[:]),
+		File: "planets/" + name,
+	})
+	c.files["/planets/"+name] = raw
+}
+
+func (c *testCatalog) publicKey() string {
+	return hex.EncodeToString(c.key.Public().(ed25519.PublicKey))
+}
+
+func (c *testCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if raw, ok := c.files[r.URL.Path]; ok {
+		_, _ = w.Write(raw)
+		return
+	}
+	if r.URL.Path != "/"+CatalogIndexFileName {
+		http.NotFound(w, r)
+		return
+	}
+	c.requests++
+	c.ifNoneMatch = r.Header.Get("If-None-Match")
+	w.Header().Set("ETag", c.etag)
+	if c.ifNoneMatch == c.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	doc, err := SignCatalog(c.index, c.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c.tamper != nil {
+		c.tamper(doc, c.key)
+	}
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+func (c *testCatalog) start(t *testing.T) configs.ZerotierCatalog {
+	t.Helper()
+	server := httptest.NewServer(c)
+	t.Cleanup(server.Close)
+	return configs.ZerotierCatalog{
+		Name:      "team",
+		Source:    server.URL + "/" + CatalogIndexFileName,
+		PublicKey: c.publicKey(),
+	}
+}
+
+func TestFetchCatalogVerifiesSignature(t *testing.T) {
+	c := newTestCatalog(t, "single-root.planet", "two-roots.planet")
+	catalog := c.start(t)
+
+	snapshot, err := FetchCatalog(catalog)
+	if err != nil {
+		t.Fatalf("fetch signed catalog: %v", err)
+	}
+	if snapshot.NotModified || snapshot.ETag != c.etag {
+		t.Fatalf("got etag %q, not modified %v", snapshot.ETag, snapshot.NotModified)
+	}
+	if len(snapshot.Planets) != 2 {
+		t.Fatalf("got %d planets, want 2", len(snapshot.Planets))
+	}
+	for _, entry := range c.index.Planets {
+		world := snapshot.Planets[entry.Id]
+		if world == nil || world.Type != ZT_WORLD_TYPE_PLANET {
+			t.Fatalf("entry %s: got %v", entry.Id, world)
+		}
+	}
+}
+
+func TestFetchCatalogRejectsBadSignature(t *testing.T) {
+	other, _, err := GenerateCatalogKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		tamper  func(doc *CatalogDocument, key ed25519.PrivateKey)
+		key     string
+		wantErr string
+	}{
+		{
+			name:    "other key",
+			key:     hex.EncodeToString(other),
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "modified index",
+			tamper: func(doc *CatalogDocument, key ed25519.PrivateKey) {
+				doc.Index = json.RawMessage(strings.Replace(string(doc.Index), `"team"`, `"evil"`, 1))
+			},
+			wantErr: "signature mismatch",
+		},
+		{
+			name:    "malformed signature",
+			tamper:  func(doc *CatalogDocument, key ed25519.PrivateKey) { doc.Signature = "zz" },
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "planet hash",
+			tamper: func(doc *CatalogDocument, key ed25519.PrivateKey) {
+				// signed by the team key, but the hash does not match the served file
+				var index CatalogIndex
+				_ = json.Unmarshal(doc.Index, &index)
+				index.Planets[0].Hash = strings.Repeat("0", 64)
+				signed, _ := SignCatalog(index, key)
+				*doc = *signed
+			},
+			wantErr: "hash mismatch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCatalog(t, "single-root.planet")
+			c.tamper = tt.tamper
+			catalog := c.start(t)
+			if tt.key != "" {
+				catalog.PublicKey = tt.key
+			}
+			_, err := FetchCatalog(catalog)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchCatalogNotModified(t *testing.T) {
+	c := newTestCatalog(t, "single-root.planet")
+	catalog := c.start(t)
+
+	snapshot, err := FetchCatalog(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog.ETag = snapshot.ETag
+	snapshot, err = FetchCatalog(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ifNoneMatch != c.etag {
+		t.Fatalf("sent If-None-Match %q, want %q", c.ifNoneMatch, c.etag)
+	}
+	if !snapshot.NotModified || snapshot.ETag != c.etag {
+		t.Fatalf("got etag %q, not modified %v", snapshot.ETag, snapshot.NotModified)
+	}
+
+	// a changed catalog gets a new ETag and is downloaded again
+	c.etag = `"v2"`
+	snapshot, err = FetchCatalog(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.NotModified || snapshot.ETag != `"v2"` || c.requests != 3 {
+		t.Fatalf("got etag %q, not modified %v after %d requests", snapshot.ETag, snapshot.NotModified, c.requests)
+	}
+}
+
+func TestFetchCatalogRejectsOversizedPlanet(t *testing.T) {
+	c := newTestCatalog(t)
+	raw := make([]byte, maxCatalogPlanetSize+1)
+	sum := sha256.Sum256(raw)
+	c.index.Planets = append(c.index.Planets, CatalogEntry{Id: "big", Hash: hex.EncodeToString(sum[:]), File: "planets/big"})
+	c.files["/planets/big"] = raw
+	_, err := FetchCatalog(c.start(t))
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("got error %v, want a size error", err)
+	}
+}
+
+func TestApplyCatalogRetiresEntries(t *testing.T) {
+	c := newTestCatalog(t, "single-root.planet", "two-roots.planet")
+	catalog := c.start(t)
+	home := t.TempDir()
+	cfg := &configs.ZerotierSwitcherProfile{ZerotierProfilePath: home, Catalogs: []configs.ZerotierCatalog{catalog}}
+
+	snapshot, err := FetchCatalog(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := ApplyCatalog(cfg, "team", snapshot); result.Added != 2 {
+		t.Fatalf("first sync: %s", result)
+	}
+	if result := ApplyCatalog(cfg, "team", snapshot); result.Unchanged != 2 {
+		t.Fatalf("second sync: %s", result)
+	}
+
+	// single-root.planet is in use, two-roots.planet is not
+	if err := os.WriteFile(filepath.Join(home, "planet"), c.files["/planets/single-root.planet"], 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.index.Planets = nil
+	c.etag = `"v2"`
+	snapshot, err = FetchCatalog(*cfg.FindCatalog("team"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ApplyCatalog(cfg, "team", snapshot)
+	if result.Retired != 1 || result.Removed != 1 {
+		t.Fatalf("retiring sync: %s", result)
+	}
+	if len(cfg.Planets) != 1 || !cfg.Planets[0].Retired || cfg.Planets[0].CatalogEntry != "single-root.planet" {
+		t.Fatalf("got planets %+v", cfg.Planets)
+	}
+
+	// the retired planet is kept once, and comes back when the catalog lists it again
+	if result := ApplyCatalog(cfg, "team", snapshot); result.Retired != 0 || len(cfg.Planets) != 1 {
+		t.Fatalf("repeated retiring sync: %s", result)
+	}
+	c.addPlanet(t, "single-root.planet")
+	c.etag = `"v3"`
+	snapshot, err = FetchCatalog(*cfg.FindCatalog("team"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := ApplyCatalog(cfg, "team", snapshot); result.Updated != 1 || cfg.Planets[0].Retired {
+		t.Fatalf("restoring sync: %s", result)
+	}
+}
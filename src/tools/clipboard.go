@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"golang.org/x/term"
+)
+
+// osc52Timeout 等待终端回复剪贴板内容的时间
+const osc52Timeout = 2 * time.Second
+
+// ReadClipboard 读取剪贴板，系统剪贴板不可用时(例如通过SSH连接)通过OSC52向终端查询
+func ReadClipboard() (string, error) {
+	text, err := clipboard.ReadAll()
+	if err == nil && text != "" {
+		return text, nil
+	}
+	text, osc52Err := ReadOSC52Clipboard()
+	if osc52Err != nil {
+		if err != nil {
+			return "", fmt.Errorf("read clipboard error: %v; %v", err, osc52Err)
+		}
+		return "", osc52Err
+	}
+	return text, nil
+}
+
+// ReadOSC52Clipboard 通过OSC52向终端查询剪贴板内容，需要终端支持并允许读取剪贴板
+func ReadOSC52Clipboard() (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal for OSC52: %v", err)
+	}
+	defer tty.Close()
+	state, err := term.MakeRaw(int(tty.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("no terminal for OSC52: %v", err)
+	}
+	defer term.Restore(int(tty.Fd()), state)
+
+	if _, err := fmt.Fprint(tty, osc52.Query().String()); err != nil {
+		return "", err
+	}
+
+	// reply: ESC ] 52 ; c ; <base64> terminated by BEL or ESC \
+	reply := make(chan []byte, 1)
+	go func() {
+		var buf []byte
+		chunk := make([]byte, 4096)
+		for {
+			n, err := tty.Read(chunk)
+			buf = append(buf, chunk[:n]...)
+			if err != nil || bytes.HasSuffix(buf, []byte("\a")) || bytes.HasSuffix(buf, []byte("\x1b\\")) {
+				reply <- buf
+				return
+			}
+		}
+	}()
+	var buf []byte
+	select {
+	case buf = <-reply:
+	case <-time.After(osc52Timeout):
+		return "", fmt.Errorf("terminal does not answer OSC52 clipboard queries")
+	}
+
+	start := bytes.Index(buf, []byte("\x1b]52;"))
+	if start < 0 {
+		return "", fmt.Errorf("unexpected OSC52 reply")
+	}
+	payload := bytes.TrimSuffix(bytes.TrimSuffix(buf[start+5:], []byte("\a")), []byte("\x1b\\"))
+	if sep := bytes.IndexByte(payload, ';'); sep >= 0 {
+		payload = payload[sep+1:]
+	}
+	data, err := base64.StdEncoding.DecodeString(string(payload))
+	if err != nil {
+		return "", fmt.Errorf("invalid OSC52 reply: %v", err)
+	}
+	return string(data), nil
+}
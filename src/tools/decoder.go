@@ -0,0 +1,351 @@
+package tools
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+var ErrTrailingData = errors.New("trailing data after the end of the world")
+
+// ParseError 解析错误，记录出错的字节偏移和字段
+type ParseError struct {
+	Offset int
+	Field  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("offset %d (%s): %v", e.Offset, e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseOptions 解析选项
+type ParseOptions struct {
+	// Lenient 宽松模式(用于分析损坏的文件)：尽可能解析，问题记录在 World.Problems 中而不是直接失败
+	Lenient bool
+}
+
+// FieldSpan 字段在原始数据中的位置，用于十六进制视图
+type FieldSpan struct {
+	Offset int
+	Length int
+	Field  string
+	Value  string // decoded value of scalar fields
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+	opts   ParseOptions
+	world  *World
+}
+
+func (d *decoder) fail(offset int, field string, err error) *ParseError {
+	return &ParseError{Offset: offset, Field: field, Err: err}
+}
+
+// problem 记录问题，严格模式下返回错误，宽松模式下继续解析
+func (d *decoder) problem(offset int, field string, err error) error {
+	pe := d.fail(offset, field, err)
+	if !d.opts.Lenient {
+		return pe
+	}
+	d.world.Problems = append(d.world.Problems, pe)
+	return nil
+}
+
+func (d *decoder) read(field string, n int) ([]byte, error) {
+	if n > len(d.data)-d.offset {
+		return nil, d.fail(d.offset, field, fmt.Errorf("need %d bytes, %d left: %w", n, len(d.data)-d.offset, io.ErrUnexpectedEOF))
+	}
+	b := d.data[d.offset : d.offset+n]
+	if n > 0 {
+		d.world.Layout = append(d.world.Layout, FieldSpan{Offset: d.offset, Length: n, Field: field})
+	}
+	d.offset += n
+	return b, nil
+}
+
+// describe 设置最后读取的字段的值
+func (d *decoder) describe(format string, args ...interface{}) {
+	if len(d.world.Layout) > 0 {
+		d.world.Layout[len(d.world.Layout)-1].Value = fmt.Sprintf(format, args...)
+	}
+}
+
+func (d *decoder) uint8(field string) (uint8, error) {
+	b, err := d.read(field, 1)
+	if err != nil {
+		return 0, err
+	}
+	d.describe("%d", b[0])
+	return b[0], nil
+}
+
+func (d *decoder) uint16(field string) (uint16, error) {
+	b, err := d.read(field, 2)
+	if err != nil {
+		return 0, err
+	}
+	d.describe("%d", binary.BigEndian.Uint16(b))
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (d *decoder) uint64(field string) (uint64, error) {
+	b, err := d.read(field, 8)
+	if err != nil {
+		return 0, err
+	}
+	d.describe("%d", binary.BigEndian.Uint64(b))
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// ParseWorld 严格解析planet/moon文件，任何错误或多余的数据都会失败
+func ParseWorld(data []byte) (*World, error) {
+	return ParseWorldWithOptions(data, ParseOptions{})
+}
+
+// ParseWorldWithOptions 按选项解析planet/moon文件
+func ParseWorldWithOptions(data []byte, opts ParseOptions) (*World, error) {
+	world := &World{
+		RawData: data,
+	}
+	d := &decoder{data: data, opts: opts, world: world}
+	err := d.parseWorld()
+	if err != nil {
+		if !opts.Lenient {
+			return nil, err
+		}
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			world.Problems = append(world.Problems, pe)
+		}
+		return world, nil
+	}
+	if d.offset < len(data) {
+		if !opts.Lenient {
+			return nil, d.fail(d.offset, "end", fmt.Errorf("%d bytes: %w", len(data)-d.offset, ErrTrailingData))
+		}
+		world.TrailingData = data[d.offset:]
+		world.Problems = append(world.Problems, d.fail(d.offset, "end", fmt.Errorf("%d bytes: %w", len(data)-d.offset, ErrTrailingData)))
+	}
+	return world, nil
+}
+
+func (d *decoder) parseWorld() error {
+	world := d.world
+	var err error
+
+	// Read type (1 byte)
+	if world.Type, err = d.uint8("type"); err != nil {
+		return err
+	}
+	if world.Type != ZT_WORLD_TYPE_PLANET && world.Type != ZT_WORLD_TYPE_MOON {
+		if err := d.problem(d.offset-1, "type", fmt.Errorf("unknown world type %d", world.Type)); err != nil {
+			return err
+		}
+	}
+
+	// Read ID (8 bytes)
+	if world.ID, err = d.uint64("id"); err != nil {
+		return err
+	}
+
+	// Read timestamp (8 bytes)
+	if world.Timestamp, err = d.uint64("timestamp"); err != nil {
+		return err
+	}
+
+	// Read update signer public key
+	b, err := d.read("update signer public key", ZT_C25519_PUBLIC_KEY_LEN)
+	if err != nil {
+		return err
+	}
+	copy(world.UpdatesMustBeSignedBy[:], b)
+
+	// Read signature
+	if b, err = d.read("signature", ZT_C25519_SIGNATURE_LEN); err != nil {
+		return err
+	}
+	copy(world.Signature[:], b)
+
+	// Read number of roots (1 byte)
+	numRoots, err := d.uint8("root count")
+	if err != nil {
+		return err
+	}
+	if numRoots > ZT_WORLD_MAX_ROOTS {
+		err := d.problem(d.offset-1, "root count", fmt.Errorf("too many roots (%d > max %d)", numRoots, ZT_WORLD_MAX_ROOTS))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Parse each root
+	for i := 0; i < int(numRoots); i++ {
+		root, err := d.parseRoot(fmt.Sprintf("root[%d]", i))
+		if root != nil {
+			world.Roots = append(world.Roots, *root)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Moons carry a dictionary (unused, always empty when signed)
+	if world.Type == ZT_WORLD_TYPE_MOON {
+		dictLen, err := d.uint16("moon dictionary length")
+		if err != nil {
+			return err
+		}
+		if _, err := d.read("moon dictionary", int(dictLen)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRoot 解析根服务器，出错时返回已解析的部分
+func (d *decoder) parseRoot(field string) (*Root, error) {
+	root := &Root{}
+
+	// Parse identity
+	identity, err := d.parseIdentity(field + ".identity")
+	if err != nil {
+		return nil, err
+	}
+	root.Identity = *identity
+
+	// Read number of endpoints (1 byte)
+	numEndpoints, err := d.uint8(field + ".endpoint count")
+	if err != nil {
+		return root, err
+	}
+
+	// Parse each endpoint
+	for i := 0; i < int(numEndpoints); i++ {
+		ep, err := d.parseInetAddress(fmt.Sprintf("%s.endpoint[%d]", field, i))
+		if err != nil {
+			return root, err
+		}
+		root.StableEndpoints = append(root.StableEndpoints, *ep)
+	}
+
+	return root, nil
+}
+
+func (d *decoder) parseIdentity(field string) (*Identity, error) {
+	identity := &Identity{}
+
+	// Read address (5 bytes)
+	b, err := d.read(field+".address", ZT_ADDRESS_LENGTH)
+	if err != nil {
+		return nil, err
+	}
+	copy(identity.Address[:], b)
+	d.describe("%s", identity.AddressString())
+
+	// Read identity type (1 byte)
+	if identity.Type, err = d.uint8(field + ".type"); err != nil {
+		return nil, err
+	}
+	keyLen, err := IdentityPublicKeyLength(identity.Type)
+	if err != nil {
+		// the length of unknown key types is unknown, parsing cannot continue
+		return nil, d.fail(d.offset-1, field+".type", err)
+	}
+
+	// Read public key (64 bytes for type 0, 114 bytes for type 1)
+	if b, err = d.read(field+".public key", keyLen); err != nil {
+		return nil, err
+	}
+	identity.PublicKey = append([]byte{}, b...)
+
+	// Skip private key if present (we don't need it)
+	privateKeyLen, err := d.uint8(field + ".private key length")
+	if err != nil {
+		return nil, err
+	}
+	if privateKeyLen > 0 {
+		expected := ZT_C25519_PRIVATE_KEY_LEN
+		if identity.Type == ZT_IDENTITY_TYPE_P384 {
+			expected = ZT_P384_PRIVATE_KEY_LEN
+		}
+		if int(privateKeyLen) != expected {
+			err := d.problem(d.offset-1, field+".private key length", fmt.Errorf("invalid private key length %d", privateKeyLen))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := d.read(field+".private key", int(privateKeyLen)); err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
+
+func (d *decoder) parseInetAddress(field string) (*InetAddress, error) {
+	addr := &InetAddress{}
+	start := d.offset
+
+	// Read address family (1 byte)
+	var err error
+	if addr.Family, err = d.uint8(field + ".family"); err != nil {
+		return nil, err
+	}
+
+	switch addr.Family {
+	case ZT_INETADDRESS_IPV4:
+		b, err := d.read(field+".ipv4", 4)
+		if err != nil {
+			return nil, err
+		}
+		addr.IP = net.IPv4(b[0], b[1], b[2], b[3])
+		d.describe("%s", addr.IP)
+	case ZT_INETADDRESS_IPV6:
+		b, err := d.read(field+".ipv6", 16)
+		if err != nil {
+			return nil, err
+		}
+		addr.IP = append(net.IP{}, b...)
+		d.describe("%s", addr.IP)
+	case 0x00:
+		// nil address
+		addr.Raw = d.data[start:d.offset]
+		return addr, nil
+	case 0x01, 0x02:
+		// Ethernet / Bluetooth addresses, accepted by ZeroTier for forward compatibility
+		if _, err := d.read(field+".address", 6); err != nil {
+			return nil, err
+		}
+		addr.Raw = d.data[start:d.offset]
+		return addr, nil
+	case 0x03:
+		// other address types carry their own length
+		n, err := d.uint16(field + ".length")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := d.read(field+".address", int(n)); err != nil {
+			return nil, err
+		}
+		addr.Raw = d.data[start:d.offset]
+		return addr, nil
+	default:
+		return nil, d.fail(start, field+".family", fmt.Errorf("unsupported address family %d", addr.Family))
+	}
+
+	// Read port (2 bytes)
+	if addr.Port, err = d.uint16(field + ".port"); err != nil {
+		return nil, err
+	}
+
+	return addr, nil
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	WorldChangeAdded    = "+"
+	WorldChangeRemoved  = "-"
+	WorldChangeModified = "~"
+)
+
+// WorldChange 两个planet之间的一处差异
+type WorldChange struct {
+	Kind  string // one of WorldChangeAdded, WorldChangeRemoved, WorldChangeModified
+	Field string
+	Old   string
+	New   string
+}
+
+func (c WorldChange) String() string {
+	switch c.Kind {
+	case WorldChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case WorldChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+// DiffWorlds 逐字段比较两个planet，根服务器按地址对应
+func DiffWorlds(a, b *World) []WorldChange {
+	var changes []WorldChange
+	modified := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, WorldChange{Kind: WorldChangeModified, Field: field, Old: old, New: new})
+		}
+	}
+	modified("type", worldTypeName(a.Type), worldTypeName(b.Type))
+	modified("id", fmt.Sprintf("%d", a.ID), fmt.Sprintf("%d", b.ID))
+	modified("timestamp", fmt.Sprintf("%d", a.Timestamp), fmt.Sprintf("%d", b.Timestamp))
+	modified("update signer", hex.EncodeToString(a.UpdatesMustBeSignedBy[:]), hex.EncodeToString(b.UpdatesMustBeSignedBy[:]))
+
+	oldRoots := map[string]Root{}
+	for _, root := range a.Roots {
+		oldRoots[root.Identity.AddressString()] = root
+	}
+	newRoots := map[string]bool{}
+	for _, root := range b.Roots {
+		address := root.Identity.AddressString()
+		newRoots[address] = true
+		oldRoot, ok := oldRoots[address]
+		if !ok {
+			changes = append(changes, WorldChange{Kind: WorldChangeAdded, Field: "root", New: root.Identity.String()})
+			for _, ep := range root.StableEndpoints {
+				changes = append(changes, WorldChange{Kind: WorldChangeAdded, Field: fmt.Sprintf("root %s endpoint", address), New: ep.String()})
+			}
+			continue
+		}
+		field := fmt.Sprintf("root %s", address)
+		modified(field+" identity", oldRoot.Identity.String(), root.Identity.String())
+		changes = append(changes, diffEndpoints(field+" endpoint", oldRoot.StableEndpoints, root.StableEndpoints)...)
+	}
+	for _, root := range a.Roots {
+		if !newRoots[root.Identity.AddressString()] {
+			changes = append(changes, WorldChange{Kind: WorldChangeRemoved, Field: "root", Old: root.Identity.String()})
+		}
+	}
+	return changes
+}
+
+func diffEndpoints(field string, a, b []InetAddress) []WorldChange {
+	var changes []WorldChange
+	var oldList, newList []string
+	oldSet, newSet := map[string]bool{}, map[string]bool{}
+	for _, ep := range a {
+		oldList = append(oldList, ep.String())
+		oldSet[ep.String()] = true
+	}
+	for _, ep := range b {
+		newList = append(newList, ep.String())
+		newSet[ep.String()] = true
+	}
+	for _, ep := range newList {
+		if !oldSet[ep] {
+			changes = append(changes, WorldChange{Kind: WorldChangeAdded, Field: field, New: ep})
+		}
+	}
+	for _, ep := range oldList {
+		if !newSet[ep] {
+			changes = append(changes, WorldChange{Kind: WorldChangeRemoved, Field: field, Old: ep})
+		}
+	}
+	if len(changes) == 0 && strings.Join(oldList, ",") != strings.Join(newList, ",") {
+		changes = append(changes, WorldChange{
+			Kind:  WorldChangeModified,
+			Field: field + " order",
+			Old:   strings.Join(oldList, ", "),
+			New:   strings.Join(newList, ", "),
+		})
+	}
+	return changes
+}
+
+func worldTypeName(t uint8) string {
+	switch t {
+	case ZT_WORLD_TYPE_PLANET:
+		return "planet"
+	case ZT_WORLD_TYPE_MOON:
+		return "moon"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
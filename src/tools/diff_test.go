@@ -0,0 +1,90 @@
+package tools
+
+import "testing"
+
+func TestDiffWorlds(t *testing.T) {
+	old, _ := readTestWorld(t, "two-roots.planet")
+	added, _, err := ParseIdentityString(knownIdentities[0].identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := func(text string) InetAddress {
+		t.Helper()
+		ep, err := ParseEndpoint(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ep
+	}
+
+	tests := []struct {
+		name string
+		edit func(w *World)
+		want []string
+	}{
+		{
+			name: "unchanged",
+			edit: func(w *World) {},
+		},
+		{
+			name: "new revision",
+			edit: func(w *World) { w.Timestamp++ },
+			want: []string{"~ timestamp: 1735689600000 -> 1735689600001"},
+		},
+		{
+			name: "endpoints",
+			edit: func(w *World) {
+				w.RemoveEndpoint(0, 1)
+				if err := w.AddEndpoint(0, endpoint("203.0.113.11/9993")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{
+				"+ root 7c69592601 endpoint: 203.0.113.11:9993",
+				"- root 7c69592601 endpoint: 2001:db8::10:9993",
+			},
+		},
+		{
+			name: "endpoint order",
+			edit: func(w *World) { w.MoveEndpoint(0, 0, 1) },
+			want: []string{"~ root 7c69592601 endpoint order: 203.0.113.10:9993, 2001:db8::10:9993 -> 2001:db8::10:9993, 203.0.113.10:9993"},
+		},
+		{
+			name: "roots",
+			edit: func(w *World) {
+				w.RemoveRoot(1)
+				if err := w.AddRoot(*added); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.AddEndpoint(1, endpoint("192.0.2.30/9993")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{
+				"+ root: " + knownIdentities[0].identity,
+				"+ root 3a46f1bf30 endpoint: 192.0.2.30:9993",
+				"- root: " + old.Roots[1].Identity.String(),
+			},
+		},
+		{
+			name: "moon of the same id",
+			edit: func(w *World) { w.Type = ZT_WORLD_TYPE_MOON },
+			want: []string{"~ type: planet -> moon"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := old.Clone()
+			tt.edit(edited)
+			changes := DiffWorlds(old, edited)
+			if len(changes) != len(tt.want) {
+				t.Fatalf("got changes %v, want %v", changes, tt.want)
+			}
+			for i, change := range changes {
+				if change.String() != tt.want[i] {
+					t.Errorf("change %d: got %q, want %q", i, change.String(), tt.want[i])
+				}
+			}
+		})
+	}
+}
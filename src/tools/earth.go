@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed earth
+var earthFiles embed.FS
+
+// ErrEarthNotBundled 当前构建没有内置官方Earth planet
+var ErrEarthNotBundled = errors.New("the official Earth planet is not bundled in this build (see src/tools/earth)")
+
+// earthSignerKeys 官方Earth的更新签名公钥(hex)，即 node/Topology.cpp 中 ZT_DEFAULT_WORLD 的 updatesMustBeSignedBy，
+// 没有内置Earth planet的构建也能识别官方Earth
+var earthSignerKeys = []string{
+	// the key has not been captured from a real ZeroTier installation yet, see src/tools/earth/README.md
+}
+
+func init() {
+	for _, text := range earthSignerKeys {
+		key, err := hex.DecodeString(text)
+		if err != nil || len(key) != ZT_C25519_PUBLIC_KEY_LEN {
+			panic(fmt.Sprintf("invalid Earth signer key %s", text))
+		}
+		var signer [ZT_C25519_PUBLIC_KEY_LEN]byte
+		copy(signer[:], key)
+		earthSigners = append(earthSigners, signer)
+	}
+	// without known keys, trust the signer of the bundled planet
+	if earth, err := OfficialEarth(); err == nil && !IsEarthSigner(earth.UpdatesMustBeSignedBy) {
+		earthSigners = append(earthSigners, earth.UpdatesMustBeSignedBy)
+	}
+}
+
+// OfficialEarth 内置的官方Earth planet
+func OfficialEarth() (*World, error) {
+	data, err := earthFiles.ReadFile("earth/planet")
+	if err != nil {
+		return nil, ErrEarthNotBundled
+	}
+	return parseEarth(data)
+}
+
+// parseEarth 解析并检查内置的Earth planet
+func parseEarth(data []byte) (*World, error) {
+	earth, err := ParseWorld(data)
+	if err != nil {
+		return nil, fmt.Errorf("bundled Earth planet: %v", err)
+	}
+	if earth.Type != ZT_WORLD_TYPE_PLANET || earth.ID != ZT_WORLD_ID_EARTH {
+		return nil, fmt.Errorf("bundled Earth planet has world ID %d", earth.ID)
+	}
+	// the default world is signed by its own update signing key
+	if !earth.VerifySignature(earth.UpdatesMustBeSignedBy) {
+		return nil, fmt.Errorf("bundled Earth planet has an invalid signature")
+	}
+	if len(earthSigners) > 0 && !IsEarthSigner(earth.UpdatesMustBeSignedBy) {
+		return nil, fmt.Errorf("bundled Earth planet is not signed by a known Earth key")
+	}
+	return earth, nil
+}
+
+// PreserveOriginalPlanet 首次使用时原样保存ZeroTier的planet文件，已保存过则不做任何操作
+func PreserveOriginalPlanet(cfg *configs.ZerotierSwitcherProfile) (bool, error) {
+	target := cfg.GetOriginalPlanetPath()
+	if _, err := os.Stat(target); err == nil {
+		return false, nil
+	}
+	data, err := os.ReadFile(configs.GetPlanetFilePath(cfg))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(target, data, 0400); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReadOriginalPlanet 读取保存的原始planet文件
+func ReadOriginalPlanet(cfg *configs.ZerotierSwitcherProfile) ([]byte, error) {
+	data, err := os.ReadFile(cfg.GetOriginalPlanetPath())
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("the original planet has not been preserved")
+	}
+	return data, err
+}
+
+// EarthWorld 恢复官方Earth使用的planet：内置的官方Earth，没有则使用保存的原始planet(如果是Earth)
+func EarthWorld(cfg *configs.ZerotierSwitcherProfile) (*World, string, error) {
+	earth, err := OfficialEarth()
+	if err == nil {
+		return earth, "bundled", nil
+	}
+	if data, rErr := ReadOriginalPlanet(cfg); rErr == nil {
+		if original, pErr := ParseWorld(data); pErr == nil && original.Type == ZT_WORLD_TYPE_PLANET && original.ID == ZT_WORLD_ID_EARTH {
+			return original, "original", nil
+		}
+	}
+	return nil, "", err
+}
+
+const (
+	EarthOfficial   = "official"
+	EarthUnverified = "unverified" // uses the Earth world ID, but no Earth signer key is known to check it
+	EarthImpostor   = "impostor"   // uses the Earth world ID with a different signer key
+)
+
+// EarthStatus 判断planet是否为官方Earth，不是Earth的world返回空
+func EarthStatus(w *World) string {
+	if w.Type != ZT_WORLD_TYPE_PLANET || w.ID != ZT_WORLD_ID_EARTH {
+		return ""
+	}
+	if len(earthSigners) == 0 {
+		return EarthUnverified
+	}
+	// the signer key is copied easily, the signature must be made by an Earth key too
+	if IsEarthSigner(w.UpdatesMustBeSignedBy) && isSignedByEarth(w) {
+		return EarthOfficial
+	}
+	return EarthImpostor
+}
+
+// isSignedByEarth planet是否由已知的Earth密钥签名
+func isSignedByEarth(w *World) bool {
+	for _, signer := range earthSigners {
+		if w.VerifySignature(signer) {
+			return true
+		}
+	}
+	return false
+}
+
+// EarthLabel Earth状态的说明文字
+func EarthLabel(status string) string {
+	switch status {
+	case EarthOfficial:
+		return "official ZeroTier Earth"
+	case EarthUnverified:
+		return "ZeroTier Earth (signer not verified)"
+	case EarthImpostor:
+		return "claims the ZeroTier Earth world ID, but is not signed by the Earth key"
+	}
+	return ""
+}
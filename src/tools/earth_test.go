@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEarth 使用测试密钥签名、带有Earth world ID的planet
+func testEarth(t *testing.T) (*World, *C25519KeyPair) {
+	t.Helper()
+	world, key := signedTestWorld(t, "two-roots.planet")
+	world.ID = ZT_WORLD_ID_EARTH
+	if err := world.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return world, key
+}
+
+func TestOfficialEarth(t *testing.T) {
+	earth, err := OfficialEarth()
+	if errors.Is(err, ErrEarthNotBundled) {
+		t.Skip("the official Earth planet is not bundled, see src/tools/earth/README.md")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earth.Type != ZT_WORLD_TYPE_PLANET || earth.ID != ZT_WORLD_ID_EARTH {
+		t.Fatalf("got world type %d, ID %d", earth.Type, earth.ID)
+	}
+	if !earth.VerifySignature(earth.UpdatesMustBeSignedBy) {
+		t.Fatal("signature does not verify")
+	}
+	for _, root := range earth.Roots {
+		if err := root.Identity.Validate(); err != nil {
+			t.Fatalf("root %s: %v", root.Identity.AddressString(), err)
+		}
+	}
+	if status := EarthStatus(earth); status != EarthOfficial {
+		t.Fatalf("bundled Earth is %s", status)
+	}
+}
+
+// withEarthSigners 测试期间替换已知的Earth签名公钥
+func withEarthSigners(t *testing.T, keys ...*C25519KeyPair) {
+	t.Helper()
+	saved := earthSigners
+	t.Cleanup(func() { earthSigners = saved })
+	earthSigners = nil
+	for _, key := range keys {
+		earthSigners = append(earthSigners, key.Public)
+	}
+}
+
+func TestEarthStatus(t *testing.T) {
+	earth, key := testEarth(t)
+	other, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an Earth of its own, and a copy of the Earth claiming its signer key but signed by another key
+	impostor, _ := testEarth(t)
+	forged := earth.Clone()
+	forged.Roots = forged.Roots[:1]
+	forged.Signature = C25519Sign(other.Private, forged.Serialize(true))
+	tests := []struct {
+		name       string
+		world      *World
+		signers    []*C25519KeyPair
+		wantStatus string
+		wantLint   string
+	}{
+		{name: "official", world: earth, signers: []*C25519KeyPair{other, key}, wantStatus: EarthOfficial},
+		{name: "other signer", world: earth, signers: []*C25519KeyPair{other}, wantStatus: EarthImpostor, wantLint: "earth-id"},
+		{name: "impostor", world: impostor, signers: []*C25519KeyPair{key}, wantStatus: EarthImpostor, wantLint: "earth-id"},
+		{name: "forged signature", world: forged, signers: []*C25519KeyPair{key}, wantStatus: EarthImpostor, wantLint: "earth-id"},
+		{name: "unverified", world: earth, wantStatus: EarthUnverified, wantLint: "earth-unverified"},
+		{name: "unverified impostor", world: impostor, wantStatus: EarthUnverified, wantLint: "earth-unverified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEarthSigners(t, tt.signers...)
+			if status := EarthStatus(tt.world); status != tt.wantStatus {
+				t.Fatalf("got status %q, want %q", status, tt.wantStatus)
+			}
+			var codes []string
+			for _, issue := range LintWorld(tt.world, true) {
+				if strings.HasPrefix(issue.Code, "earth-") {
+					codes = append(codes, issue.Code)
+				}
+			}
+			if strings.Join(codes, ",") != tt.wantLint {
+				t.Fatalf("got lint issues %v, want %q", codes, tt.wantLint)
+			}
+		})
+	}
+
+	withEarthSigners(t, key)
+	data, err := os.ReadFile(filepath.Join("testdata", "worlds", "single-root.planet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	world, err := ParseWorld(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := EarthStatus(world); status != "" {
+		t.Fatalf("got status %q for another world", status)
+	}
+}
+
+func TestParseEarth(t *testing.T) {
+	withEarthSigners(t)
+	earth, _ := testEarth(t)
+	parsed, err := parseEarth(earth.Serialize(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ID != ZT_WORLD_ID_EARTH || parsed.UpdatesMustBeSignedBy != earth.UpdatesMustBeSignedBy {
+		t.Fatalf("got world %d", parsed.ID)
+	}
+
+	other := *earth
+	other.ID++
+	tampered := *earth
+	tampered.Timestamp++
+	moon, err := os.ReadFile(filepath.Join("testdata", "worlds", "0000007c69592601.moon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "other world", data: other.Serialize(false), wantErr: "world ID"},
+		{name: "moon", data: moon, wantErr: "world ID"},
+		{name: "bad signature", data: tampered.Serialize(false), wantErr: "invalid signature"},
+		{name: "truncated", data: earth.Serialize(false)[:100], wantErr: "bundled Earth planet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseEarth(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// with known keys, the bundled planet must be signed by one of them
+	stranger, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withEarthSigners(t, stranger)
+	if _, err := parseEarth(earth.Serialize(false)); err == nil || !strings.Contains(err.Error(), "known Earth key") {
+		t.Fatalf("got error %v for an unknown signer", err)
+	}
+}
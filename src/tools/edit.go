@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ZT_WORLD_MAX_STABLE_ENDPOINTS_PER_ROOT = 32
+
+// Clone 复制planet，用于编辑
+func (w World) Clone() *World {
+	clone := w
+	clone.Roots = make([]Root, len(w.Roots))
+	for i, root := range w.Roots {
+		clone.Roots[i] = Root{
+			Identity:        root.Identity,
+			StableEndpoints: append([]InetAddress{}, root.StableEndpoints...),
+		}
+	}
+	clone.RawData = nil
+	clone.TrailingData = nil
+	clone.Problems = nil
+	clone.Layout = nil
+	return &clone
+}
+
+// AddRoot 添加根服务器(不含地址)
+func (w *World) AddRoot(identity Identity) error {
+	if len(w.Roots) >= ZT_WORLD_MAX_ROOTS {
+		return fmt.Errorf("a world can have at most %d roots", ZT_WORLD_MAX_ROOTS)
+	}
+	for _, root := range w.Roots {
+		if root.Identity.Address == identity.Address {
+			return fmt.Errorf("root %s already exists", identity.AddressString())
+		}
+	}
+	if err := identity.Validate(); err != nil && !errors.Is(err, ErrIdentityValidationUnsupported) {
+		return err
+	}
+	w.Roots = append(w.Roots, Root{Identity: identity})
+	return nil
+}
+
+// RemoveRoot 删除根服务器
+func (w *World) RemoveRoot(index int) {
+	if index >= 0 && index < len(w.Roots) {
+		w.Roots = append(w.Roots[:index], w.Roots[index+1:]...)
+	}
+}
+
+// AddEndpoint 为根服务器添加地址
+func (w *World) AddEndpoint(rootIndex int, ep InetAddress) error {
+	root := &w.Roots[rootIndex]
+	if len(root.StableEndpoints) >= ZT_WORLD_MAX_STABLE_ENDPOINTS_PER_ROOT {
+		return fmt.Errorf("a root can have at most %d stable endpoints", ZT_WORLD_MAX_STABLE_ENDPOINTS_PER_ROOT)
+	}
+	for _, existing := range root.StableEndpoints {
+		if existing.String() == ep.String() {
+			return fmt.Errorf("endpoint %s already exists", ep.String())
+		}
+	}
+	root.StableEndpoints = append(root.StableEndpoints, ep)
+	return nil
+}
+
+// RemoveEndpoint 删除根服务器的地址
+func (w *World) RemoveEndpoint(rootIndex, index int) {
+	root := &w.Roots[rootIndex]
+	if index >= 0 && index < len(root.StableEndpoints) {
+		root.StableEndpoints = append(root.StableEndpoints[:index], root.StableEndpoints[index+1:]...)
+	}
+}
+
+// MoveEndpoint 调整地址的顺序，返回新的位置
+func (w *World) MoveEndpoint(rootIndex, index, delta int) int {
+	endpoints := w.Roots[rootIndex].StableEndpoints
+	target := index + delta
+	if index < 0 || index >= len(endpoints) || target < 0 || target >= len(endpoints) {
+		return index
+	}
+	endpoints[index], endpoints[target] = endpoints[target], endpoints[index]
+	return target
+}
+
+// BumpTimestamp 将时间戳更新为当前时间(至少比原来大1)
+func (w *World) BumpTimestamp() {
+	now := uint64(time.Now().UnixMilli())
+	if now <= w.Timestamp {
+		now = w.Timestamp + 1
+	}
+	w.Timestamp = now
+}
+
+// Sign 使用密钥对签名，密钥必须是planet指定的更新签名密钥
+func (w *World) Sign(key *C25519KeyPair) error {
+	if !bytes.Equal(key.Public[:], w.UpdatesMustBeSignedBy[:]) {
+		return fmt.Errorf("the key is not the update signing key of this world, nodes would reject the update")
+	}
+	w.Signature = C25519Sign(key.Private, w.Serialize(true))
+	w.RawData = w.Serialize(false)
+	return nil
+}
+
+// ValidateEdit 检查编辑后的planet是否可以保存
+func (w *World) ValidateEdit() error {
+	if len(w.Roots) == 0 {
+		return fmt.Errorf("a world needs at least one root")
+	}
+	if len(w.Roots) > ZT_WORLD_MAX_ROOTS {
+		return fmt.Errorf("a world can have at most %d roots", ZT_WORLD_MAX_ROOTS)
+	}
+	for _, root := range w.Roots {
+		if len(root.StableEndpoints) == 0 {
+			return fmt.Errorf("root %s has no stable endpoints", root.Identity.AddressString())
+		}
+	}
+	return nil
+}
+
+// ParseEndpoint 解析地址，支持 ip/port (ZeroTier的格式)、ip:port 和 [ipv6]:port
+func ParseEndpoint(text string) (InetAddress, error) {
+	text = strings.TrimSpace(text)
+	host, portText, ok := strings.Cut(text, "/")
+	if !ok {
+		var err error
+		if host, portText, err = net.SplitHostPort(text); err != nil {
+			return InetAddress{}, fmt.Errorf("invalid endpoint (%s), use ip/port", text)
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return InetAddress{}, fmt.Errorf("invalid ip address (%s)", host)
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil || port == 0 {
+		return InetAddress{}, fmt.Errorf("invalid port (%s)", portText)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return InetAddress{Family: ZT_INETADDRESS_IPV4, IP: ip4, Port: uint16(port)}, nil
+	}
+	return InetAddress{Family: ZT_INETADDRESS_IPV6, IP: ip, Port: uint16(port)}, nil
+}
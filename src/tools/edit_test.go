@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr string
+	}{
+		{text: "192.0.2.1/9993", want: "192.0.2.1:9993"},
+		{text: " 192.0.2.1:9993 ", want: "192.0.2.1:9993"},
+		{text: "2001:db8::1/9993", want: "2001:db8::1:9993"},
+		{text: "[2001:db8::1]:9993", want: "2001:db8::1:9993"},
+		{text: "192.0.2.1", wantErr: "invalid endpoint"},
+		{text: "example.com/9993", wantErr: "invalid ip address"},
+		{text: "192.0.2.1/0", wantErr: "invalid port"},
+		{text: "192.0.2.1/65536", wantErr: "invalid port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			ep, err := ParseEndpoint(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ep.String() != tt.want {
+				t.Fatalf("got %s, want %s", ep.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEditWorld(t *testing.T) {
+	original, key := signedTestWorld(t, "two-roots.planet")
+	raw := append([]byte{}, original.RawData...)
+	identity, _, err := ParseIdentityString(knownIdentities[0].identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ep, err := ParseEndpoint("192.0.2.30/9993")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := original.Clone()
+	if err := w.AddRoot(*identity); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddRoot(*identity); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("got error %v for a duplicate root", err)
+	}
+	forged := *identity
+	forged.Address[4] ^= 1
+	if err := w.AddRoot(forged); err == nil || !strings.Contains(err.Error(), "does not match the public key") {
+		t.Fatalf("got error %v for an invalid identity", err)
+	}
+	if err := w.ValidateEdit(); err == nil || !strings.Contains(err.Error(), "has no stable endpoints") {
+		t.Fatalf("got error %v for a root without endpoints", err)
+	}
+	if err := w.AddEndpoint(2, ep); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddEndpoint(2, ep); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("got error %v for a duplicate endpoint", err)
+	}
+	if err := w.ValidateEdit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// endpoints only move inside the list
+	if got := w.MoveEndpoint(0, 0, 1); got != 1 || w.Roots[0].StableEndpoints[0].String() != "2001:db8::10:9993" {
+		t.Fatalf("moved to %d: %v", got, w.Roots[0].StableEndpoints)
+	}
+	if got := w.MoveEndpoint(0, 1, 1); got != 1 {
+		t.Fatalf("moved past the end to %d", got)
+	}
+	w.RemoveEndpoint(0, 0)
+	w.RemoveRoot(1)
+	w.RemoveRoot(5)
+	if len(w.Roots) != 2 || len(w.Roots[0].StableEndpoints) != 1 || w.Roots[1].Identity.Address != identity.Address {
+		t.Fatalf("got roots %+v", w.Roots)
+	}
+
+	// editing the clone leaves the original world alone
+	if len(original.Roots) != 2 || len(original.Roots[0].StableEndpoints) != 2 || !bytes.Equal(original.Serialize(false), raw) {
+		t.Fatal("the original world changed")
+	}
+
+	w.BumpTimestamp()
+	if w.Timestamp <= original.Timestamp {
+		t.Fatalf("timestamp %d is not after %d", w.Timestamp, original.Timestamp)
+	}
+	other, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sign(other); err == nil || w.RawData != nil {
+		t.Fatalf("signed with another key: %v", err)
+	}
+	if err := w.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseWorld(w.RawData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.IsUpdateOf(original) {
+		t.Fatal("the signed edit is not an update of the original world")
+	}
+	if len(DiffWorlds(parsed, w)) != 0 {
+		t.Fatalf("the signed world differs: %v", DiffWorlds(parsed, w))
+	}
+}
+
+func TestValidateEdit(t *testing.T) {
+	w, _ := readTestWorld(t, "single-root.planet")
+	w.Roots = nil
+	if err := w.ValidateEdit(); err == nil || !strings.Contains(err.Error(), "at least one root") {
+		t.Fatalf("got error %v for a world without roots", err)
+	}
+	w.Roots = make([]Root, ZT_WORLD_MAX_ROOTS+1)
+	if err := w.ValidateEdit(); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Fatalf("got error %v for too many roots", err)
+	}
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	ExportFormatBinary  = "binary"
+	ExportFormatBase64  = "base64"
+	ExportFormatHex     = "hex"
+	ExportFormatCpp     = "cpp"
+	ExportFormatJSON    = "json"
+	ExportFormatMkworld = "mkworld"
+)
+
+// ExportFormats 支持的导出格式
+var ExportFormats = []string{ExportFormatBinary, ExportFormatBase64, ExportFormatHex, ExportFormatCpp, ExportFormatJSON, ExportFormatMkworld}
+
+// WorldJSON 解析后的planet的JSON描述
+type WorldJSON struct {
+	Type                  string          `json:"type"`
+	ID                    uint64          `json:"id"`
+	Timestamp             uint64          `json:"timestamp"`
+	UpdatesMustBeSignedBy string          `json:"updatesMustBeSignedBy"`
+	Signature             string          `json:"signature"`
+	Roots                 []WorldJSONRoot `json:"roots"`
+}
+
+type WorldJSONRoot struct {
+	Address         string   `json:"address"`
+	IdentityType    string   `json:"identityType"`
+	Identity        string   `json:"identity"`
+	StableEndpoints []string `json:"stableEndpoints"`
+}
+
+// MkworldConfig mkworld的JSON配置(mkworld_custom 的格式)，签名密钥文件需要与配置放在一起
+type MkworldConfig struct {
+	RootNodes   []MkworldRootNode `json:"rootNodes"`
+	Signing     []string          `json:"signing"`
+	Output      string            `json:"output"`
+	PlID        uint64            `json:"plID"`
+	PlBirth     uint64            `json:"plBirth"`
+	PlRecommend bool              `json:"plRecommend"`
+}
+
+type MkworldRootNode struct {
+	Comments  string   `json:"comments"`
+	Identity  string   `json:"identity"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// MakeWorldJSON 生成planet的JSON描述
+func MakeWorldJSON(w *World) WorldJSON {
+	doc := WorldJSON{
+		Type:                  worldTypeName(w.Type),
+		ID:                    w.ID,
+		Timestamp:             w.Timestamp,
+		UpdatesMustBeSignedBy: hex.EncodeToString(w.UpdatesMustBeSignedBy[:]),
+		Signature:             hex.EncodeToString(w.Signature[:]),
+		Roots:                 []WorldJSONRoot{},
+	}
+	for _, root := range w.Roots {
+		doc.Roots = append(doc.Roots, WorldJSONRoot{
+			Address:         root.Identity.AddressString(),
+			IdentityType:    root.Identity.TypeName(),
+			Identity:        root.Identity.String(),
+			StableEndpoints: endpointStrings(root.StableEndpoints),
+		})
+	}
+	return doc
+}
+
+// MakeMkworldConfig 生成mkworld的配置，用于重新生成相同的planet
+func MakeMkworldConfig(w *World) MkworldConfig {
+	cfg := MkworldConfig{
+		Signing:     []string{"previous.c25519", "current.c25519"},
+		Output:      "planet.custom",
+		PlID:        w.ID,
+		PlBirth:     w.Timestamp,
+		PlRecommend: false,
+	}
+	for _, root := range w.Roots {
+		cfg.RootNodes = append(cfg.RootNodes, MkworldRootNode{
+			Comments:  root.Identity.AddressString(),
+			Identity:  root.Identity.String(),
+			Endpoints: endpointStrings(root.StableEndpoints),
+		})
+	}
+	return cfg
+}
+
+// FormatCppArray 生成与ZeroTier的 Topology.cpp 中默认world相同格式的C++数组(与mkworld的输出相同)
+func FormatCppArray(data []byte) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#define ZT_DEFAULT_WORLD_LENGTH %d\n", len(data)))
+	sb.WriteString("static const unsigned char ZT_DEFAULT_WORLD[ZT_DEFAULT_WORLD_LENGTH] = {")
+	for i, b := range data {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(fmt.Sprintf("0x%02x", b))
+	}
+	sb.WriteString("};\n")
+	return sb.String()
+}
+
+// ExportWorld 按格式导出planet
+func ExportWorld(w *World, format string) ([]byte, error) {
+	switch format {
+	case ExportFormatBinary:
+		return w.RawData, nil
+	case ExportFormatBase64:
+		return []byte(w.ToBase64() + "\n"), nil
+	case ExportFormatHex:
+		return []byte(hex.EncodeToString(w.RawData) + "\n"), nil
+	case ExportFormatCpp:
+		return []byte(FormatCppArray(w.RawData)), nil
+	case ExportFormatJSON:
+		return marshalExportJSON(MakeWorldJSON(w))
+	case ExportFormatMkworld:
+		// moons are generated by genmoon from their definition
+		if w.Type == ZT_WORLD_TYPE_MOON {
+			return marshalExportJSON(MakeMoonDefinition(w, nil))
+		}
+		return marshalExportJSON(MakeMkworldConfig(w))
+	default:
+		return nil, fmt.Errorf("unsupported export format (%s), use one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+func marshalExportJSON(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ExportFileName 导出文件的默认文件名
+func ExportFileName(w *World, format string) string {
+	base := "planet"
+	if w.Type == ZT_WORLD_TYPE_MOON {
+		base = strings.TrimSuffix(MoonFileName(w), ".moon")
+	}
+	switch format {
+	case ExportFormatBinary:
+		if w.Type == ZT_WORLD_TYPE_MOON {
+			return MoonFileName(w)
+		}
+		return base
+	case ExportFormatBase64:
+		return base + ".b64"
+	case ExportFormatHex:
+		return base + ".hex"
+	case ExportFormatCpp:
+		return base + ".h"
+	case ExportFormatJSON:
+		return base + ".json"
+	default:
+		if w.Type == ZT_WORLD_TYPE_MOON {
+			return base + ".moon.json"
+		}
+		return base + ".mkworld.json"
+	}
+}
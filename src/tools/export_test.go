@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportWorld(t *testing.T) {
+	for _, name := range []string{"two-roots.planet", "0000007c69592601.moon"} {
+		world, raw := readTestWorld(t, name)
+		t.Run(name, func(t *testing.T) {
+			export := func(format string) []byte {
+				t.Helper()
+				data, err := ExportWorld(world, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			}
+
+			if !bytes.Equal(export(ExportFormatBinary), raw) {
+				t.Fatal("binary export differs from the world")
+			}
+			// text exports decode back to the same bytes
+			for format, textFormat := range map[string]string{
+				ExportFormatBase64: TextFormatBase64,
+				ExportFormatHex:    TextFormatHex,
+				ExportFormatCpp:    TextFormatCArray,
+			} {
+				decoded, got, err := DecodeWorldText(string(export(format)))
+				if err != nil || got != textFormat || !bytes.Equal(decoded.RawData, raw) {
+					t.Fatalf("%s export decoded as %s: %v", format, got, err)
+				}
+			}
+
+			var doc WorldJSON
+			if err := json.Unmarshal(export(ExportFormatJSON), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if doc.ID != world.ID || doc.Timestamp != world.Timestamp || len(doc.Roots) != len(world.Roots) ||
+				doc.Roots[0].Identity != world.Roots[0].Identity.String() || doc.Roots[0].StableEndpoints[0] != "203.0.113.10/9993" {
+				t.Fatalf("got JSON %+v", doc)
+			}
+
+			mkworld := export(ExportFormatMkworld)
+			if world.Type == ZT_WORLD_TYPE_MOON {
+				var def MoonDefinition
+				if err := json.Unmarshal(mkworld, &def); err != nil {
+					t.Fatal(err)
+				}
+				if def.ID != "0000007c69592601" || def.WorldType != "moon" || def.SigningKeySecret != "" || def.Roots[0].Identity != doc.Roots[0].Identity {
+					t.Fatalf("got moon definition %+v", def)
+				}
+				return
+			}
+			var cfg MkworldConfig
+			if err := json.Unmarshal(mkworld, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.PlID != world.ID || cfg.PlBirth != world.Timestamp || len(cfg.RootNodes) != 2 ||
+				strings.Join(cfg.RootNodes[0].Endpoints, ",") != "203.0.113.10/9993,2001:db8::10/9993" {
+				t.Fatalf("got mkworld config %+v", cfg)
+			}
+		})
+	}
+
+	world, _ := readTestWorld(t, "single-root.planet")
+	if _, err := ExportWorld(world, "yaml"); err == nil || !strings.Contains(err.Error(), "unsupported export format") {
+		t.Fatalf("got error %v for an unknown format", err)
+	}
+}
+
+func TestFormatCppArray(t *testing.T) {
+	want := "#define ZT_DEFAULT_WORLD_LENGTH 3\n" +
+		"static const unsigned char ZT_DEFAULT_WORLD[ZT_DEFAULT_WORLD_LENGTH] = {0x01,0x00,0xff};\n"
+	if got := FormatCppArray([]byte{0x01, 0x00, 0xff}); got != want {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	planet, _ := readTestWorld(t, "single-root.planet")
+	moon, _ := readTestWorld(t, "0000007c69592601.moon")
+	tests := []struct {
+		format string
+		planet string
+		moon   string
+	}{
+		{ExportFormatBinary, "planet", "0000007c69592601.moon"},
+		{ExportFormatBase64, "planet.b64", "0000007c69592601.b64"},
+		{ExportFormatHex, "planet.hex", "0000007c69592601.hex"},
+		{ExportFormatCpp, "planet.h", "0000007c69592601.h"},
+		{ExportFormatJSON, "planet.json", "0000007c69592601.json"},
+		{ExportFormatMkworld, "planet.mkworld.json", "0000007c69592601.moon.json"},
+	}
+	for _, tt := range tests {
+		if got := ExportFileName(planet, tt.format); got != tt.planet {
+			t.Errorf("%s: got planet file %s, want %s", tt.format, got, tt.planet)
+		}
+		if got := ExportFileName(moon, tt.format); got != tt.moon {
+			t.Errorf("%s: got moon file %s, want %s", tt.format, got, tt.moon)
+		}
+	}
+}
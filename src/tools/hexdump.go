@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// HexBytesPerRow 十六进制视图每行显示的字节数
+const HexBytesPerRow = 16
+
+// HexRow 十六进制视图中的一行，每行只包含一个字段的数据
+type HexRow struct {
+	Offset  int
+	Bytes   []byte
+	Field   string // set on the first row of a field
+	Value   string
+	Index   int    // index of the field, used to alternate highlighting
+	Problem string // parse problem located in this row
+}
+
+func (r HexRow) String() string {
+	label := r.Field
+	if r.Value != "" {
+		label += " = " + r.Value
+	}
+	if r.Problem != "" {
+		if label != "" {
+			label += "  "
+		}
+		label += "<- " + r.Problem
+	}
+	return strings.TrimRight(fmt.Sprintf("%08x  %-*s  %s", r.Offset, HexBytesPerRow*3-1, r.HexString(), label), " ")
+}
+
+// HexString 以空格分隔的十六进制字节
+func (r HexRow) HexString() string {
+	parts := make([]string, len(r.Bytes))
+	for i, b := range r.Bytes {
+		parts[i] = hex.EncodeToString([]byte{b})
+	}
+	return strings.Join(parts, " ")
+}
+
+// HexLayout 按字段划分planet的原始数据，未能解析的数据和多余的数据单独标记
+func HexLayout(w *World) []HexRow {
+	var rows []HexRow
+	addSpan := func(index int, span FieldSpan) {
+		for offset := span.Offset; offset < span.Offset+span.Length; offset += HexBytesPerRow {
+			end := offset + HexBytesPerRow
+			if end > span.Offset+span.Length {
+				end = span.Offset + span.Length
+			}
+			row := HexRow{Offset: offset, Bytes: w.RawData[offset:end], Index: index}
+			if offset == span.Offset {
+				row.Field = span.Field
+				row.Value = span.Value
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	end := 0
+	for i, span := range w.Layout {
+		addSpan(i, span)
+		end = span.Offset + span.Length
+	}
+	if end < len(w.RawData) {
+		field := "unparsed"
+		if w.TrailingData != nil {
+			field = "trailing data"
+		}
+		addSpan(len(w.Layout), FieldSpan{Offset: end, Length: len(w.RawData) - end, Field: field})
+	}
+
+	for _, problem := range w.Problems {
+		located := false
+		for i := range rows {
+			if problem.Offset >= rows[i].Offset && problem.Offset < rows[i].Offset+len(rows[i].Bytes) {
+				if rows[i].Problem != "" {
+					rows[i].Problem += "; "
+				}
+				rows[i].Problem += problem.Error()
+				located = true
+				break
+			}
+		}
+		if !located {
+			rows = append(rows, HexRow{Offset: problem.Offset, Field: "end of data", Index: len(w.Layout) + 1, Problem: problem.Error()})
+		}
+	}
+	return rows
+}
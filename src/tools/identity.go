@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/salsa20/salsa"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ZT_ADDRESS_LENGTH                             = 5
+	ZT_ADDRESS_RESERVED_PREFIX                    = 0xff
+	ZT_IDENTITY_GEN_MEMORY                        = 2097152
+	ZT_IDENTITY_GEN_HASHCASH_FIRST_BYTE_LESS_THAN = 17
+)
+
+var identityBase32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ErrIdentityValidationUnsupported 无法在本地校验的身份类型
+var ErrIdentityValidationUnsupported = errors.New("validation of P-384 identities is not supported")
+
+// salsa20Stream Salsa20/20 密钥流，按64字节块连续加密
+type salsa20Stream struct {
+	key     [32]byte
+	counter [16]byte // 8 byte nonce followed by the little endian block counter
+	block   uint64
+}
+
+func newSalsa20Stream(key, iv []byte) *salsa20Stream {
+	s := &salsa20Stream{}
+	copy(s.key[:], key)
+	copy(s.counter[:8], iv)
+	return s
+}
+
+// crypt 加密(异或)数据，长度必须是64的倍数
+func (s *salsa20Stream) crypt(data []byte) {
+	binary.LittleEndian.PutUint64(s.counter[8:], s.block)
+	salsa.XORKeyStream(data, data, &s.counter, &s.key)
+	s.block += uint64(len(data) / 64)
+}
+
+// computeMemoryHardHash ZeroTier从公钥推导地址时使用的内存困难哈希
+func computeMemoryHardHash(publicKey []byte) [64]byte {
+	digest := sha512.Sum512(publicKey)
+
+	// 以类似CBC的方式填充内存，保证只能顺序计算
+	genmem := make([]byte, ZT_IDENTITY_GEN_MEMORY)
+	s20 := newSalsa20Stream(digest[:32], digest[32:40])
+	s20.crypt(genmem[:64])
+	for i := 64; i < ZT_IDENTITY_GEN_MEMORY; i += 64 {
+		copy(genmem[i:i+64], genmem[i-64:i])
+		s20.crypt(genmem[i : i+64])
+	}
+
+	// 以填充的内存作为查找表生成最终的摘要
+	var tmp [8]byte
+	for i := 0; i < ZT_IDENTITY_GEN_MEMORY/8; i += 2 {
+		idx1 := binary.BigEndian.Uint64(genmem[i*8:]) % 8
+		idx2 := binary.BigEndian.Uint64(genmem[(i+1)*8:]) % (ZT_IDENTITY_GEN_MEMORY / 8)
+		copy(tmp[:], genmem[idx2*8:idx2*8+8])
+		copy(genmem[idx2*8:idx2*8+8], digest[idx1*8:idx1*8+8])
+		copy(digest[idx1*8:idx1*8+8], tmp[:])
+		s20.crypt(digest[:])
+	}
+	return digest
+}
+
+var identityValidationCache = struct {
+	sync.Mutex
+	results map[string]error
+}{results: map[string]error{}}
+
+// Validate 校验地址是否由公钥推导得到(与 zerotier-idtool validate 相同)
+func (i *Identity) Validate() error {
+	if i.Address[0] == ZT_ADDRESS_RESERVED_PREFIX || i.Address == [ZT_ADDRESS_LENGTH]byte{} {
+		return fmt.Errorf("address %s is reserved", i.AddressString())
+	}
+
+	if i.Type != ZT_IDENTITY_TYPE_C25519 {
+		return ErrIdentityValidationUnsupported
+	}
+
+	cacheKey := i.String()
+	identityValidationCache.Lock()
+	err, ok := identityValidationCache.results[cacheKey]
+	identityValidationCache.Unlock()
+	if ok {
+		return err
+	}
+
+	digest := computeMemoryHardHash(i.PublicKey)
+	expected := hex.EncodeToString(digest[59:64])
+	switch {
+	case digest[0] >= ZT_IDENTITY_GEN_HASHCASH_FIRST_BYTE_LESS_THAN:
+		err = fmt.Errorf("public key of %s does not satisfy the identity proof of work", i.AddressString())
+	case expected != i.AddressString():
+		err = fmt.Errorf("address %s does not match the public key (expected %s)", i.AddressString(), expected)
+	}
+
+	identityValidationCache.Lock()
+	identityValidationCache.results[cacheKey] = err
+	identityValidationCache.Unlock()
+	return err
+}
+
+// decodeIdentityKey 解码身份中的密钥，P-384身份使用base32，同时兼容hex
+func decodeIdentityKey(text string, size int) ([]byte, error) {
+	if key, err := hex.DecodeString(text); err == nil && len(key) == size {
+		return key, nil
+	}
+	key, err := identityBase32.DecodeString(text)
+	if err != nil || len(key) != size {
+		return nil, fmt.Errorf("invalid key length")
+	}
+	return key, nil
+}
+
+// ParseIdentityString 解析 identity.public / identity.secret 格式的身份 (address:type:public[:private])
+func ParseIdentityString(text string) (*Identity, []byte, error) {
+	fields := strings.Split(strings.TrimSpace(text), ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, nil, fmt.Errorf("invalid identity format")
+	}
+	identity := &Identity{}
+	address, err := hex.DecodeString(fields[0])
+	if err != nil || len(address) != ZT_ADDRESS_LENGTH {
+		return nil, nil, fmt.Errorf("invalid identity address")
+	}
+	copy(identity.Address[:], address)
+
+	privateKeyLen := ZT_C25519_PRIVATE_KEY_LEN
+	switch fields[1] {
+	case "0":
+		identity.Type = ZT_IDENTITY_TYPE_C25519
+	case "1":
+		identity.Type = ZT_IDENTITY_TYPE_P384
+		privateKeyLen = ZT_P384_PRIVATE_KEY_LEN
+	default:
+		return nil, nil, fmt.Errorf("unsupported identity type %s (only 0=C25519 and 1=P-384 are supported)", fields[1])
+	}
+	publicKeyLen, _ := IdentityPublicKeyLength(identity.Type)
+	if identity.PublicKey, err = decodeIdentityKey(fields[2], publicKeyLen); err != nil {
+		return nil, nil, fmt.Errorf("invalid identity public key: %v", err)
+	}
+	var privateKey []byte
+	if len(fields) == 4 {
+		if privateKey, err = decodeIdentityKey(fields[3], privateKeyLen); err != nil {
+			return nil, nil, fmt.Errorf("invalid identity private key: %v", err)
+		}
+	}
+	return identity, privateKey, nil
+}
+
+// ReadIdentityFile 读取 identity.public / identity.secret 文件
+func ReadIdentityFile(filename string) (*Identity, []byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read file (%s) error: %v", filename, err)
+	}
+	return ParseIdentityString(string(content))
+}
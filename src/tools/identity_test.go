@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// knownIdentities 公开的ZeroTier根服务器身份(zerotier-idtool生成的 identity.public)
+var knownIdentities = []struct {
+	identity string
+	address  string
+}{
+	{
+		identity: "3a46f1bf30:0:76e66fab33e28549a62ee2064d1843273c2c300ba45c3f20bef02dbad225723bb59a9bb4b13535730961aeecf5a163ace477cceb0727025b99ac14a5166a09a3",
+		address:  "3a46f1bf30",
+	},
+	{
+		identity: "de8950a8b2:0:1b3ada8251b91b6b6fa6535b8c7e2460918f4f729abdec97d3c7f3796868fb02f0de0b0ee554b2d59fc3524743eebfcf5315e790ed6d92db5bd10c28c09b40ef",
+		address:  "de8950a8b2",
+	},
+	{
+		identity: "992fcf1db7:0:206ed59350b31916f749a1f85dffb3a8787dcbf83b8c6e9448d4e3ea0e3369301be716c3609344a9d1533850fb4460c50af43322bcfc8e13d3301a1f1003ceb6",
+		address:  "992fcf1db7",
+	},
+}
+
+func TestIdentityValidateKnownIdentities(t *testing.T) {
+	for _, tt := range knownIdentities {
+		t.Run(tt.address, func(t *testing.T) {
+			identity, privateKey, err := ParseIdentityString(tt.identity + "\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if privateKey != nil {
+				t.Fatal("identity.public has no private key")
+			}
+			if identity.String() != tt.identity {
+				t.Fatalf("got %s after a round trip", identity.String())
+			}
+			if err := identity.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			digest := computeMemoryHardHash(identity.PublicKey)
+			if derived := hex.EncodeToString(digest[59:64]); derived != tt.address {
+				t.Fatalf("derived address %s, want %s", derived, tt.address)
+			}
+		})
+	}
+}
+
+func TestIdentityValidateRejectsMismatch(t *testing.T) {
+	known := knownIdentities[0].identity
+	tests := []struct {
+		name     string
+		identity string
+		wantErr  string
+	}{
+		{
+			name:     "other address",
+			identity: "3a46f1bf31" + known[10:],
+			wantErr:  "does not match the public key (expected 3a46f1bf30)",
+		},
+		{
+			name:     "modified public key",
+			identity: known[:len(known)-1] + "4",
+			wantErr:  "3a46f1bf30",
+		},
+		{
+			name:     "reserved address",
+			identity: "ff46f1bf30" + known[10:],
+			wantErr:  "is reserved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, _, err := ParseIdentityString(tt.identity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = identity.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testP384Identity 测试用的P-384身份(公钥内容为任意字节，P-384身份不能在本地校验)
+func testP384Identity() *Identity {
+	identity := &Identity{Type: ZT_IDENTITY_TYPE_P384, Address: [ZT_ADDRESS_LENGTH]byte{0x12, 0x34, 0x56, 0x78, 0x9a}}
+	identity.PublicKey = make([]byte, ZT_P384_PUBLIC_KEY_LEN)
+	for i := range identity.PublicKey {
+		identity.PublicKey[i] = byte(i)
+	}
+	return identity
+}
+
+func TestP384IdentityString(t *testing.T) {
+	want := testP384Identity()
+	public := "123456789a:1:" + identityBase32.EncodeToString(want.PublicKey)
+	private := make([]byte, ZT_P384_PRIVATE_KEY_LEN)
+	tests := []struct {
+		name        string
+		text        string
+		wantPrivate bool
+		wantErr     string
+	}{
+		{name: "identity.public", text: public},
+		{name: "identity.secret", text: public + ":" + identityBase32.EncodeToString(private), wantPrivate: true},
+		{name: "hex public key", text: "123456789a:1:" + hex.EncodeToString(want.PublicKey)},
+		{name: "C25519 key length", text: "123456789a:1:" + identityBase32.EncodeToString(want.PublicKey[:ZT_C25519_PUBLIC_KEY_LEN]), wantErr: "invalid identity public key"},
+		{name: "C25519 private key", text: public + ":" + identityBase32.EncodeToString(private[:ZT_C25519_PRIVATE_KEY_LEN]), wantErr: "invalid identity private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, privateKey, err := ParseIdentityString(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Type != ZT_IDENTITY_TYPE_P384 || identity.TypeName() != "P-384" || !bytes.Equal(identity.PublicKey, want.PublicKey) {
+				t.Fatalf("got identity %+v", identity)
+			}
+			if (privateKey != nil) != tt.wantPrivate {
+				t.Fatalf("got private key %x", privateKey)
+			}
+			if identity.String() != public {
+				t.Fatalf("got %s after a round trip", identity.String())
+			}
+			if err := identity.Validate(); !errors.Is(err, ErrIdentityValidationUnsupported) {
+				t.Fatalf("got validation error %v", err)
+			}
+		})
+	}
+}
+
+func TestP384IdentityInWorld(t *testing.T) {
+	world, key := signedTestWorld(t, "two-roots.planet")
+	identity := testP384Identity()
+	if err := world.AddRoot(*identity); err != nil {
+		t.Fatal(err)
+	}
+	ep, err := ParseEndpoint("192.0.2.40/9993")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := world.AddEndpoint(2, ep); err != nil {
+		t.Fatal(err)
+	}
+	if err := world.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// address, type, 114 byte public key, no private key
+	var buf bytes.Buffer
+	identity.Serialize(&buf)
+	if buf.Len() != ZT_ADDRESS_LENGTH+1+ZT_P384_PUBLIC_KEY_LEN+1 || !bytes.Contains(world.RawData, buf.Bytes()) {
+		t.Fatalf("serialized identity %x not found in the world", buf.Bytes())
+	}
+
+	parsed, err := ParseWorld(world.RawData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := parsed.Roots[2].Identity
+	if root.Type != ZT_IDENTITY_TYPE_P384 || root.String() != identity.String() {
+		t.Fatalf("got root %s", root.String())
+	}
+	if !bytes.Equal(parsed.Serialize(false), world.RawData) || !parsed.VerifySignature(key.Public) {
+		t.Fatal("the world changed after a round trip")
+	}
+	if codes := lintCodes(LintWorld(parsed, true)); len(codes) != 1 || codes[0] != "info/unverified-identity" {
+		t.Fatalf("got lint issues %v", codes)
+	}
+}
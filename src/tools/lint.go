@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintInfo    = "info"
+)
+
+// LintIssue planet检查发现的问题
+type LintIssue struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s [%s] %s", i.Severity, i.Code, i.Message)
+}
+
+// earthSigners 已知的官方Earth签名公钥
+var earthSigners [][ZT_C25519_PUBLIC_KEY_LEN]byte
+
+var cgnatNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// timestampTolerance 允许的时钟误差
+const timestampTolerance = 10 * time.Minute
+
+// LintWorld 检查planet中常见的问题，asPlanet为true时按照planet文件的用途检查
+func LintWorld(w *World, asPlanet bool) []LintIssue {
+	var issues []LintIssue
+	add := func(severity, code, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// only set when parsed in lenient mode
+	for _, problem := range w.Problems {
+		add(LintError, "malformed", "%v", problem)
+	}
+
+	switch w.Type {
+	case ZT_WORLD_TYPE_PLANET:
+	case ZT_WORLD_TYPE_MOON:
+		if asPlanet {
+			add(LintError, "moon-as-planet", "world %d is a moon, ZeroTier will not load it as a planet", w.ID)
+		}
+	default:
+		add(LintError, "unknown-type", "unknown world type %d", w.Type)
+	}
+
+	switch EarthStatus(w) {
+	case EarthImpostor:
+		add(LintWarning, "earth-id", "uses the ZeroTier Earth world ID but is not signed by the Earth key")
+	case EarthUnverified:
+		add(LintInfo, "earth-unverified", "uses the ZeroTier Earth world ID, the Earth key is not bundled to verify it")
+	}
+
+	now := uint64(time.Now().Add(timestampTolerance).UnixMilli())
+	if w.Timestamp > now {
+		add(LintWarning, "future-timestamp", "timestamp %d is in the future", w.Timestamp)
+	}
+
+	if len(w.Roots) == 0 {
+		add(LintError, "no-roots", "world has no roots")
+	}
+
+	seenRoots := map[string]int{}
+	seenEndpoints := map[string]int{}
+	for i, root := range w.Roots {
+		address := root.Identity.AddressString()
+		if j, ok := seenRoots[address]; ok {
+			add(LintError, "identical-roots", "root %d and root %d have the same identity (%s)", j+1, i+1, address)
+		}
+		seenRoots[address] = i
+		for j := 0; j < i; j++ {
+			if bytes.Equal(w.Roots[j].Identity.PublicKey, root.Identity.PublicKey) && w.Roots[j].Identity.Address != root.Identity.Address {
+				add(LintError, "identical-roots", "root %d and root %d share the same public key", j+1, i+1)
+			}
+		}
+
+		if err := root.Identity.Validate(); errors.Is(err, ErrIdentityValidationUnsupported) {
+			add(LintInfo, "unverified-identity", "root %d: %v", i+1, err)
+		} else if err != nil {
+			add(LintError, "invalid-identity", "root %d: %v", i+1, err)
+		}
+		if len(root.StableEndpoints) == 0 {
+			add(LintError, "root-without-endpoints", "root %s has no stable endpoints", address)
+		}
+		for _, ep := range root.StableEndpoints {
+			name := ep.String()
+			if _, ok := seenEndpoints[name]; ok {
+				add(LintWarning, "duplicate-endpoint", "endpoint %s of root %s is duplicated", name, address)
+			}
+			seenEndpoints[name] = i
+			for _, issue := range lintEndpoint(ep) {
+				issue.Message = fmt.Sprintf("endpoint %s of root %s %s", name, address, issue.Message)
+				issues = append(issues, issue)
+			}
+		}
+	}
+	return issues
+}
+
+func lintEndpoint(ep InetAddress) []LintIssue {
+	var issues []LintIssue
+	if ep.Port == 0 {
+		issues = append(issues, LintIssue{Severity: LintError, Code: "port-zero", Message: "has port 0"})
+	}
+	switch {
+	case ep.IP == nil:
+		issues = append(issues, LintIssue{Severity: LintError, Code: "invalid-endpoint", Message: "has no address"})
+	case ep.IP.IsUnspecified():
+		issues = append(issues, LintIssue{Severity: LintError, Code: "unspecified-endpoint", Message: "is an unspecified address"})
+	case ep.IP.IsLoopback():
+		issues = append(issues, LintIssue{Severity: LintError, Code: "loopback-endpoint", Message: "is a loopback address"})
+	case ep.IP.IsMulticast():
+		issues = append(issues, LintIssue{Severity: LintError, Code: "multicast-endpoint", Message: "is a multicast address"})
+	case ep.IP.IsLinkLocalUnicast():
+		issues = append(issues, LintIssue{Severity: LintWarning, Code: "private-endpoint", Message: "is a link-local address"})
+	case ep.IP.IsPrivate() || cgnatNetwork.Contains(ep.IP):
+		issues = append(issues, LintIssue{Severity: LintWarning, Code: "private-endpoint", Message: "is a private address, unreachable outside the local network"})
+	}
+	return issues
+}
+
+// IsEarthSigner 判断是否为官方Earth的签名公钥
+func IsEarthSigner(key [ZT_C25519_PUBLIC_KEY_LEN]byte) bool {
+	for _, signer := range earthSigners {
+		if signer == key {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLintErrors 是否包含错误级别的问题
+func HasLintErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
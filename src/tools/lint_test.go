@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lintCodes 问题的 severity/code 列表
+func lintCodes(issues []LintIssue) []string {
+	var codes []string
+	for _, issue := range issues {
+		codes = append(codes, issue.Severity+"/"+issue.Code)
+	}
+	return codes
+}
+
+func TestLintWorld(t *testing.T) {
+	planet, _ := readTestWorld(t, "two-roots.planet")
+	moon, _ := readTestWorld(t, "0000007c69592601.moon")
+	endpoint := func(text string) InetAddress {
+		t.Helper()
+		ep, err := ParseEndpoint(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ep
+	}
+	edited := func(edit func(w *World)) *World {
+		w := planet.Clone()
+		edit(w)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		world    *World
+		asPlanet bool
+		want     []string
+	}{
+		{name: "planet", world: planet, asPlanet: true},
+		{name: "moon", world: moon},
+		{name: "moon as planet", world: moon, asPlanet: true, want: []string{"error/moon-as-planet"}},
+		{
+			name:  "future timestamp",
+			world: edited(func(w *World) { w.Timestamp = uint64(time.Now().Add(time.Hour).UnixMilli()) }),
+			want:  []string{"warning/future-timestamp"},
+		},
+		{name: "no roots", world: edited(func(w *World) { w.Roots = nil }), want: []string{"error/no-roots"}},
+		{
+			name:  "identical roots",
+			world: edited(func(w *World) { w.Roots[1].Identity = w.Roots[0].Identity }),
+			want:  []string{"error/identical-roots"},
+		},
+		{
+			name:  "shared public key",
+			world: edited(func(w *World) { w.Roots[1].Identity.PublicKey = w.Roots[0].Identity.PublicKey }),
+			want:  []string{"error/identical-roots", "error/invalid-identity"},
+		},
+		{
+			name:  "invalid identity",
+			world: edited(func(w *World) { w.Roots[0].Identity.Address[0] ^= 0xff }),
+			want:  []string{"error/invalid-identity"},
+		},
+		{
+			name:  "root without endpoints",
+			world: edited(func(w *World) { w.Roots[1].StableEndpoints = nil }),
+			want:  []string{"error/root-without-endpoints"},
+		},
+		{
+			name: "endpoints",
+			world: edited(func(w *World) {
+				w.Roots[0].StableEndpoints = []InetAddress{
+					endpoint("127.0.0.1/9993"),
+					endpoint("0.0.0.0/9993"),
+					endpoint("224.0.0.1/9993"),
+					endpoint("fe80::1/9993"),
+					endpoint("10.0.0.1/9993"),
+					endpoint("100.64.0.1/9993"),
+				}
+				w.Roots[1].StableEndpoints = append(w.Roots[1].StableEndpoints, endpoint("203.0.113.10/9993"), planet.Roots[1].StableEndpoints[0])
+			}),
+			want: []string{
+				"error/loopback-endpoint",
+				"error/unspecified-endpoint",
+				"error/multicast-endpoint",
+				"warning/private-endpoint",
+				"warning/private-endpoint",
+				"warning/private-endpoint",
+				"warning/duplicate-endpoint",
+			},
+		},
+		{
+			name:  "port zero",
+			world: edited(func(w *World) { w.Roots[0].StableEndpoints[0].Port = 0 }),
+			want:  []string{"error/port-zero"},
+		},
+		{
+			name:     "malformed",
+			world:    edited(func(w *World) { w.Problems = []*ParseError{{Offset: 8, Field: "timestamp", Err: io.ErrUnexpectedEOF}} }),
+			asPlanet: true,
+			want:     []string{"error/malformed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := LintWorld(tt.world, tt.asPlanet)
+			if got := strings.Join(lintCodes(issues), " "); got != strings.Join(tt.want, " ") {
+				t.Fatalf("got %v, want %v", issues, tt.want)
+			}
+			if HasLintErrors(issues) != strings.Contains(strings.Join(tt.want, " "), "error/") {
+				t.Fatalf("HasLintErrors is %v for %v", HasLintErrors(issues), issues)
+			}
+		})
+	}
+}
+
+func TestLintEarth(t *testing.T) {
+	earth, key := testEarth(t)
+	impostor, _ := testEarth(t)
+
+	withEarthSigners(t)
+	if got := lintCodes(LintWorld(earth, true)); len(got) != 1 || got[0] != "info/earth-unverified" {
+		t.Fatalf("without known signers got %v", got)
+	}
+	withEarthSigners(t, key)
+	if got := lintCodes(LintWorld(earth, true)); len(got) != 0 {
+		t.Fatalf("official Earth got %v", got)
+	}
+	if got := lintCodes(LintWorld(impostor, true)); len(got) != 1 || got[0] != "warning/earth-id" {
+		t.Fatalf("impostor got %v", got)
+	}
+}
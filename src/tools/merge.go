@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"strings"
+)
+
+// MergeField 冲突的设置项
+type MergeField struct {
+	Name   string
+	Local  string
+	Backup string
+}
+
+// MergeConflict 备份与当前配置中同一个world的条目设置不同
+type MergeConflict struct {
+	Index     int // index of the local entry in the profile
+	Local     configs.ZerotierPlanetFile
+	Backup    configs.ZerotierPlanetFile
+	Fields    []MergeField
+	UseBackup bool // the local settings are kept by default
+}
+
+// MergePlan 合并备份的计划，Apply之前可以修改冲突的处理方式
+type MergePlan struct {
+	Added       []configs.ZerotierPlanetFile // worlds not in the profile yet
+	Revisions   int                          // revisions added to existing entries
+	Duplicates  int                          // revisions already in the profile
+	Conflicts   []*MergeConflict
+	Catalogs    []configs.ZerotierCatalog
+	SigningKeys []configs.ZerotierSigningKey
+	Skipped     []string // invalid entries, and catalogs or keys clashing with the local ones
+	revisions   []pendingRevision
+}
+
+type pendingRevision struct {
+	index    int
+	revision configs.ZerotierPlanetRevision
+}
+
+// IsEmpty 备份中没有需要合并的内容
+func (p *MergePlan) IsEmpty() bool {
+	return len(p.Added) == 0 && p.Revisions == 0 && len(p.Conflicts) == 0 && len(p.Catalogs) == 0 && len(p.SigningKeys) == 0
+}
+
+// Summary 合并计划的概要
+func (p *MergePlan) Summary() string {
+	return fmt.Sprintf(
+		"%d new planets, %d new revisions, %d duplicates, %d conflicts, %d catalogs, %d signing keys, %d skipped",
+		len(p.Added), p.Revisions, p.Duplicates, len(p.Conflicts), len(p.Catalogs), len(p.SigningKeys), len(p.Skipped),
+	)
+}
+
+// PlanMerge 计算把备份合并到当前配置的计划，按内容hash去重，不会修改配置
+func PlanMerge(cfg *configs.ZerotierSwitcherProfile, backup *configs.ZerotierSwitcherProfile) *MergePlan {
+	plan := &MergePlan{}
+	for _, bp := range backup.Planets {
+		bp, head, revisions, err := normalizeBackupPlanet(bp)
+		if err != nil {
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("planet %s: %v", bp.Remark, err))
+			continue
+		}
+		idx := findPlanetOfRevisions(cfg, revisions)
+		if idx < 0 {
+			idx = FindPlanetOfWorld(cfg, head)
+		}
+		if idx < 0 {
+			if bp.Catalog != "" && cfg.FindCatalog(bp.Catalog) != nil {
+				plan.Skipped = append(plan.Skipped, fmt.Sprintf("planet %s: managed by catalog %s, sync the catalog instead", bp.Remark, bp.Catalog))
+				continue
+			}
+			plan.Added = append(plan.Added, bp)
+			continue
+		}
+
+		local := cfg.Planets[idx]
+		for _, rev := range revisions {
+			if local.FindRevision(rev.Hash) != nil {
+				plan.Duplicates++
+			} else if !local.IsReadOnly() {
+				plan.Revisions++
+				plan.revisions = append(plan.revisions, pendingRevision{index: idx, revision: rev})
+			}
+		}
+		// catalog entries are kept in sync by the catalog
+		if local.IsReadOnly() {
+			continue
+		}
+		if fields := diffPlanetSettings(local, bp); len(fields) > 0 {
+			plan.Conflicts = append(plan.Conflicts, &MergeConflict{Index: idx, Local: local, Backup: bp, Fields: fields})
+		}
+	}
+
+	for _, catalog := range backup.Catalogs {
+		local := cfg.FindCatalog(catalog.Name)
+		if local == nil {
+			plan.Catalogs = append(plan.Catalogs, catalog)
+		} else if local.Source != catalog.Source || local.PublicKey != catalog.PublicKey {
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("catalog %s: differs from the subscribed one", catalog.Name))
+		}
+	}
+
+	for _, key := range backup.SigningKeys {
+		if hasSigningKey(cfg, key.PublicKey) {
+			continue
+		}
+		if cfg.FindSigningKey(key.Name) != nil {
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("signing key %s: a different key with the same name exists", key.Name))
+			continue
+		}
+		plan.SigningKeys = append(plan.SigningKeys, key)
+	}
+	return plan
+}
+
+// Apply 按计划合并到配置
+func (p *MergePlan) Apply(cfg *configs.ZerotierSwitcherProfile) {
+	for _, pending := range p.revisions {
+		cfg.Planets[pending.index].AddRevision(pending.revision)
+	}
+	for _, conflict := range p.Conflicts {
+		if !conflict.UseBackup {
+			continue
+		}
+		planet := &cfg.Planets[conflict.Index]
+		planet.Remark = conflict.Backup.Remark
+		planet.AutoJoinNetwork = conflict.Backup.AutoJoinNetwork
+		planet.Networks = conflict.Backup.Networks
+		planet.Tags = conflict.Backup.Tags
+	}
+	cfg.Planets = append(cfg.Planets, p.Added...)
+	cfg.Catalogs = append(cfg.Catalogs, p.Catalogs...)
+	cfg.SigningKeys = append(cfg.SigningKeys, p.SigningKeys...)
+}
+
+// normalizeBackupPlanet 解析备份中的条目，版本hash按内容重新计算
+func normalizeBackupPlanet(bp configs.ZerotierPlanetFile) (configs.ZerotierPlanetFile, *World, []configs.ZerotierPlanetRevision, error) {
+	head, err := ParsePlanetBase64(bp.Data)
+	if err != nil {
+		return bp, nil, nil, err
+	}
+	bp.Hash = MakePlanetRevision(head).Hash
+	revisions := []configs.ZerotierPlanetRevision{bp.HeadRevision()}
+	bp.Revisions = append([]configs.ZerotierPlanetRevision{}, bp.Revisions...)
+	for i, rev := range bp.Revisions {
+		world, err := ParsePlanetBase64(rev.Data)
+		if err != nil {
+			return bp, nil, nil, fmt.Errorf("revision %d: %v", rev.CreateTime, err)
+		}
+		if world.ID != head.ID || world.Type != head.Type {
+			return bp, nil, nil, fmt.Errorf("revision %d belongs to another world", rev.CreateTime)
+		}
+		bp.Revisions[i].Hash = MakePlanetRevision(world).Hash
+		revisions = append(revisions, bp.Revisions[i])
+	}
+	return bp, head, revisions, nil
+}
+
+// findPlanetOfRevisions 查找已经包含其中某个版本的条目，没有则返回-1
+func findPlanetOfRevisions(cfg *configs.ZerotierSwitcherProfile, revisions []configs.ZerotierPlanetRevision) int {
+	for i, p := range cfg.Planets {
+		for _, rev := range revisions {
+			if p.FindRevision(rev.Hash) != nil {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func diffPlanetSettings(local, backup configs.ZerotierPlanetFile) []MergeField {
+	var fields []MergeField
+	add := func(name, l, b string) {
+		if l != b {
+			fields = append(fields, MergeField{Name: name, Local: l, Backup: b})
+		}
+	}
+	add("remark", local.Remark, backup.Remark)
+	add("auto join network", local.AutoJoinNetwork, backup.AutoJoinNetwork)
+	add("networks", strings.Join(local.Networks, ","), strings.Join(backup.Networks, ","))
+	add("tags", strings.Join(local.Tags, ","), strings.Join(backup.Tags, ","))
+	return fields
+}
+
+func hasSigningKey(cfg *configs.ZerotierSwitcherProfile, publicKey string) bool {
+	for _, key := range cfg.SigningKeys {
+		if strings.EqualFold(key.PublicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"encoding/hex"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"strings"
+	"testing"
+)
+
+func TestMergeBackup(t *testing.T) {
+	v2, key := signedTestWorld(t, "two-roots.planet")
+	v1 := nextRevision(t, v2, key, -1)
+	single, _ := readTestWorld(t, "single-root.planet")
+	moon, _ := readTestWorld(t, "0000007c69592601.moon")
+	newKey := func() string {
+		pair, err := GenerateC25519KeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(pair.Public[:])
+	}
+	localKey := newKey()
+
+	cfg := newTestProfile(t)
+	team := MakePlanetFile(v1, "team")
+	team.AutoJoinNetwork = "8056c2e21c000001"
+	managed := MakePlanetFile(moon, "moon")
+	managed.Catalog = "lab"
+	cfg.Planets = []configs.ZerotierPlanetFile{team, managed}
+	cfg.Catalogs = []configs.ZerotierCatalog{{Name: "lab", Source: "https://lab.example.com/catalog"}}
+	cfg.SigningKeys = []configs.ZerotierSigningKey{{Name: "team", PublicKey: localKey}}
+
+	// the backup knows the team world with a newer head and other settings
+	backupTeam := MakePlanetFile(v2, "office")
+	// hashes are recomputed from the content
+	backupTeam.Hash = "stale"
+	backupTeam.Revisions = []configs.ZerotierPlanetRevision{MakePlanetRevision(v1)}
+	backupManaged := MakePlanetFile(moon, "renamed")
+	catalogPlanet := MakePlanetFile(single, "from lab")
+	catalogPlanet.Catalog = "lab"
+	broken := configs.ZerotierPlanetFile{Remark: "broken", Data: "not base64!"}
+	backup := &configs.ZerotierSwitcherProfile{
+		Planets: []configs.ZerotierPlanetFile{backupTeam, backupManaged, catalogPlanet, broken, MakePlanetFile(single, "single")},
+		Catalogs: []configs.ZerotierCatalog{
+			{Name: "lab", Source: "https://other.example.com/catalog"},
+			{Name: "office", Source: "https://office.example.com/catalog"},
+		},
+		SigningKeys: []configs.ZerotierSigningKey{
+			{Name: "team", PublicKey: strings.ToUpper(localKey)},
+			{Name: "team", PublicKey: newKey()},
+			{Name: "backup", PublicKey: newKey()},
+		},
+	}
+
+	plan := PlanMerge(cfg, backup)
+	if len(plan.Added) != 1 || plan.Added[0].Remark != "single" {
+		t.Fatalf("added %+v", plan.Added)
+	}
+	// v1 of the team world and the moon are already in the profile
+	if plan.Revisions != 1 || plan.Duplicates != 2 {
+		t.Fatalf("got %d revisions, %d duplicates", plan.Revisions, plan.Duplicates)
+	}
+	if len(plan.Conflicts) != 1 {
+		t.Fatalf("got conflicts %+v", plan.Conflicts)
+	}
+	conflict := plan.Conflicts[0]
+	var fields []string
+	for _, field := range conflict.Fields {
+		fields = append(fields, field.Name+"="+field.Local+"/"+field.Backup)
+	}
+	if conflict.Index != 0 || strings.Join(fields, " ") != "remark=team/office auto join network=8056c2e21c000001/" {
+		t.Fatalf("got conflict fields %v", fields)
+	}
+	if len(plan.Catalogs) != 1 || plan.Catalogs[0].Name != "office" || len(plan.SigningKeys) != 1 || plan.SigningKeys[0].Name != "backup" {
+		t.Fatalf("got catalogs %+v, keys %+v", plan.Catalogs, plan.SigningKeys)
+	}
+	skipped := strings.Join(plan.Skipped, "\n")
+	for _, want := range []string{
+		"planet from lab: managed by catalog lab",
+		"planet broken:",
+		"catalog lab: differs",
+		"signing key team: a different key",
+	} {
+		if !strings.Contains(skipped, want) {
+			t.Errorf("%q not skipped in:\n%s", want, skipped)
+		}
+	}
+	if len(plan.Skipped) != 4 || plan.IsEmpty() {
+		t.Fatalf("got plan %s", plan.Summary())
+	}
+	if len(cfg.Planets) != 2 || len(cfg.Planets[0].Revisions) != 0 {
+		t.Fatal("planning changed the profile")
+	}
+
+	// the local settings are kept unless the backup is chosen
+	plan.Apply(cfg)
+	if len(cfg.Planets) != 3 || cfg.Planets[2].Remark != "single" {
+		t.Fatalf("got planets %+v", cfg.Planets)
+	}
+	teamEntry := cfg.Planets[0]
+	if teamEntry.Remark != "team" || teamEntry.AutoJoinNetwork != "8056c2e21c000001" {
+		t.Fatalf("local settings changed: %+v", teamEntry)
+	}
+	if teamEntry.Hash != MakePlanetRevision(v2).Hash || len(teamEntry.Revisions) != 1 || teamEntry.Revisions[0].Hash != MakePlanetRevision(v1).Hash {
+		t.Fatalf("got revisions of %+v", teamEntry)
+	}
+	if cfg.Planets[1].Remark != "moon" || len(cfg.Planets[1].Revisions) != 0 {
+		t.Fatalf("the catalog entry changed: %+v", cfg.Planets[1])
+	}
+	if len(cfg.Catalogs) != 2 || len(cfg.SigningKeys) != 2 {
+		t.Fatalf("got %d catalogs, %d keys", len(cfg.Catalogs), len(cfg.SigningKeys))
+	}
+	if plan := PlanMerge(cfg, backup); plan.Revisions != 0 || len(plan.Added) != 0 || len(plan.Catalogs) != 0 || len(plan.SigningKeys) != 0 {
+		t.Fatalf("merging again plans %s", plan.Summary())
+	}
+}
+
+func TestMergeConflictUseBackup(t *testing.T) {
+	world, _ := readTestWorld(t, "single-root.planet")
+	cfg := newTestProfile(t)
+	cfg.Planets = []configs.ZerotierPlanetFile{MakePlanetFile(world, "local")}
+	entry := MakePlanetFile(world, "backup")
+	entry.Tags = []string{"team"}
+	entry.AutoJoinNetwork = "8056c2e21c000001"
+
+	plan := PlanMerge(cfg, &configs.ZerotierSwitcherProfile{Planets: []configs.ZerotierPlanetFile{entry}})
+	if len(plan.Conflicts) != 1 || plan.Duplicates != 1 || plan.Revisions != 0 {
+		t.Fatalf("got plan %s", plan.Summary())
+	}
+	plan.Conflicts[0].UseBackup = true
+	plan.Apply(cfg)
+	got := cfg.Planets[0]
+	if len(cfg.Planets) != 1 || got.Remark != "backup" || got.AutoJoinNetwork != "8056c2e21c000001" || strings.Join(got.Tags, ",") != "team" {
+		t.Fatalf("got entry %+v", got)
+	}
+}
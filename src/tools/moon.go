@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MoonDefinition moon的定义，格式与 zerotier-idtool initmoon 的输出相同
+type MoonDefinition struct {
+	ID                    string               `json:"id"`
+	ObjType               string               `json:"objtype"`
+	Roots                 []MoonDefinitionRoot `json:"roots"`
+	SigningKey            string               `json:"signingKey"`
+	SigningKeySecret      string               `json:"signingKey_SECRET,omitempty"`
+	UpdatesMustBeSignedBy string               `json:"updatesMustBeSignedBy"`
+	WorldType             string               `json:"worldType"`
+}
+
+type MoonDefinitionRoot struct {
+	Identity        string   `json:"identity"`
+	StableEndpoints []string `json:"stableEndpoints"`
+}
+
+// CreateMoon 由根服务器的身份和地址生成并签名moon，moon的ID为身份的地址
+func CreateMoon(identity Identity, endpoints []InetAddress, signer *C25519KeyPair) (*World, error) {
+	if identity.Type != ZT_IDENTITY_TYPE_C25519 {
+		return nil, fmt.Errorf("moons can only be created for C25519 (type 0) identities")
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("a moon needs at least one stable endpoint")
+	}
+	world := &World{
+		Type:                  ZT_WORLD_TYPE_MOON,
+		UpdatesMustBeSignedBy: signer.Public,
+	}
+	for _, b := range identity.Address {
+		world.ID = world.ID<<8 | uint64(b)
+	}
+	if err := world.AddRoot(identity); err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints {
+		if err := world.AddEndpoint(0, ep); err != nil {
+			return nil, err
+		}
+	}
+	world.Timestamp = uint64(time.Now().UnixMilli())
+	if err := world.Sign(signer); err != nil {
+		return nil, err
+	}
+	return ParseWorld(world.RawData)
+}
+
+// MakeMoonDefinition 生成moon的定义，给出signer时包含签名私钥(与initmoon相同)
+func MakeMoonDefinition(world *World, signer *C25519KeyPair) MoonDefinition {
+	def := MoonDefinition{
+		ID:                    fmt.Sprintf("%016x", world.ID),
+		ObjType:               "world",
+		SigningKey:            hex.EncodeToString(world.UpdatesMustBeSignedBy[:]),
+		UpdatesMustBeSignedBy: hex.EncodeToString(world.UpdatesMustBeSignedBy[:]),
+		WorldType:             "moon",
+		Roots:                 []MoonDefinitionRoot{},
+	}
+	if signer != nil {
+		def.SigningKey = hex.EncodeToString(signer.Public[:])
+		def.SigningKeySecret = hex.EncodeToString(signer.Private[:])
+	}
+	for _, root := range world.Roots {
+		def.Roots = append(def.Roots, MoonDefinitionRoot{Identity: root.Identity.String(), StableEndpoints: endpointStrings(root.StableEndpoints)})
+	}
+	return def
+}
+
+// endpointStrings 以ZeroTier的 ip/port 格式输出地址
+func endpointStrings(endpoints []InetAddress) []string {
+	list := []string{}
+	for _, ep := range endpoints {
+		if ep.IP != nil {
+			list = append(list, fmt.Sprintf("%s/%d", ep.IP.String(), ep.Port))
+		}
+	}
+	return list
+}
+
+// MoonFileName moon文件名，与genmoon生成的文件名相同
+func MoonFileName(world *World) string {
+	return fmt.Sprintf("%016x.moon", world.ID)
+}
+
+// InstallMoon 将moon写入ZeroTier的 moons.d 目录并重启服务
+func InstallMoon(cfg *configs.ZerotierSwitcherProfile, base64Moon string, callback func(int, string)) error {
+	callback(1, "Decoding moon")
+	world, err := ParsePlanetBase64(base64Moon)
+	if err != nil {
+		return err
+	}
+	if world.Type != ZT_WORLD_TYPE_MOON {
+		return fmt.Errorf("not a moon")
+	}
+
+	callback(2, "Get moons.d path")
+	moonsPath := filepath.Join(cfg.ZerotierHome(), "moons.d")
+
+	callback(3, "Checking moon file")
+	moonPath := filepath.Join(moonsPath, MoonFileName(world))
+	if existing, err := os.ReadFile(moonPath); err == nil && base64.StdEncoding.EncodeToString(existing) == base64Moon {
+		return fmt.Errorf("same moon file, abort")
+	}
+
+	callback(4, "Writing moon file")
+	// the ZeroTier home itself must exist, only moons.d is created
+	if err := os.Mkdir(moonsPath, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("create moons.d error: %v", err)
+	}
+	if err := os.WriteFile(moonPath, world.RawData, 0644); err != nil {
+		return fmt.Errorf("write moon file error: %v", err)
+	}
+
+	if !cfg.RestartOnActivate() {
+		callback(7, "Done, restart the zerotier service to load the moon")
+		return nil
+	}
+	callback(5, "Restarting zerotier service, please wait")
+	if err := restartZeroTierService(cfg); err != nil {
+		return fmt.Errorf("restart zerotier service error: %v", err)
+	}
+	callback(7, "Done")
+	return nil
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testMoon 为已知的根服务器身份生成moon
+func testMoon(t *testing.T) (*World, *C25519KeyPair) {
+	t.Helper()
+	identity, _, err := ParseIdentityString(knownIdentities[0].identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var endpoints []InetAddress
+	for _, text := range []string{"192.0.2.50/9993", "2001:db8::50/9993"} {
+		ep, err := ParseEndpoint(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		endpoints = append(endpoints, ep)
+	}
+	signer, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	moon, err := CreateMoon(*identity, endpoints, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return moon, signer
+}
+
+func TestCreateMoon(t *testing.T) {
+	moon, signer := testMoon(t)
+	if moon.Type != ZT_WORLD_TYPE_MOON || MoonFileName(moon) != "0000003a46f1bf30.moon" {
+		t.Fatalf("got world type %d, file %s", moon.Type, MoonFileName(moon))
+	}
+	if !moon.VerifySignature(signer.Public) || moon.UpdatesMustBeSignedBy != signer.Public {
+		t.Fatal("the moon is not signed by the signer")
+	}
+	parsed, err := ParseWorld(moon.RawData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Serialize(false), moon.RawData) || len(DiffWorlds(moon, parsed)) != 0 {
+		t.Fatal("the moon changed after a round trip")
+	}
+	if issues := LintWorld(parsed, false); len(issues) != 0 {
+		t.Fatalf("got lint issues %v", issues)
+	}
+
+	def := MakeMoonDefinition(moon, nil)
+	if def.ID != "0000003a46f1bf30" || def.SigningKeySecret != "" || len(def.Roots) != 1 ||
+		def.Roots[0].Identity != knownIdentities[0].identity ||
+		strings.Join(def.Roots[0].StableEndpoints, ",") != "192.0.2.50/9993,2001:db8::50/9993" {
+		t.Fatalf("got definition %+v", def)
+	}
+
+	p384 := testP384Identity()
+	if _, err := CreateMoon(*p384, moon.Roots[0].StableEndpoints, signer); err == nil {
+		t.Fatal("created a moon for a P-384 identity")
+	}
+	if _, err := CreateMoon(moon.Roots[0].Identity, nil, signer); err == nil {
+		t.Fatal("created a moon without endpoints")
+	}
+}
+
+func TestInstallMoonWithoutRestart(t *testing.T) {
+	moon, _ := testMoon(t)
+	cfg := newTestProfile(t)
+	if err := configs.FindProfileSetting("activate_restart").Apply(cfg, "no"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(cfg.ZerotierHome(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(moon.RawData)
+
+	var messages []string
+	callback := func(step int, message string) { messages = append(messages, message) }
+	if err := InstallMoon(cfg, encoded, callback); err != nil {
+		t.Fatal(err)
+	}
+	if last := messages[len(messages)-1]; !strings.Contains(last, "restart the zerotier service") {
+		t.Fatalf("last message %q", last)
+	}
+	for _, message := range messages {
+		if strings.HasPrefix(message, "Restarting") {
+			t.Fatalf("reported %q with restarts disabled", message)
+		}
+	}
+	installed, err := os.ReadFile(filepath.Join(cfg.ZerotierHome(), "moons.d", MoonFileName(moon)))
+	if err != nil || !bytes.Equal(installed, moon.RawData) {
+		t.Fatalf("installed moon differs: %v", err)
+	}
+	if err := InstallMoon(cfg, encoded, callback); err == nil || !strings.Contains(err.Error(), "same moon") {
+		t.Fatalf("got error %v when installing the moon again", err)
+	}
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+)
+
+// PlanetUpdate 根服务器推送的planet更新
+type PlanetUpdate struct {
+	Index    int // index of the stored entry in ZerotierSwitcherProfile.Planets
+	World    *World
+	Previous *World
+}
+
+// DetectPlanetUpdate 检查当前使用的planet文件是否为已保存条目的新版本，没有则返回nil
+func DetectPlanetUpdate(cfg *configs.ZerotierSwitcherProfile) (*PlanetUpdate, error) {
+	world, err := ParsePlanetFile(configs.GetPlanetFilePath(cfg))
+	if err != nil {
+		return nil, err
+	}
+	hash := configs.PlanetHash(world.RawData)
+	for _, p := range cfg.Planets {
+		if p.FindRevision(hash) != nil {
+			return nil, nil
+		}
+	}
+
+	var update *PlanetUpdate
+	for i, p := range cfg.Planets {
+		// 目录管理的planet由目录负责更新
+		if p.IsReadOnly() || p.WorldId != world.ID || p.WorldType != world.Type {
+			continue
+		}
+		stored, err := ParsePlanetBase64(p.Data)
+		if err != nil || !world.IsUpdateOf(stored) {
+			continue
+		}
+		if update == nil || stored.Timestamp > update.Previous.Timestamp {
+			update = &PlanetUpdate{Index: i, World: world, Previous: stored}
+		}
+	}
+	return update, nil
+}
+
+// ApplyPlanetUpdate 将更新写入条目，原来的版本保存到历史中
+func ApplyPlanetUpdate(cfg *configs.ZerotierSwitcherProfile, update *PlanetUpdate) {
+	cfg.Planets[update.Index].AddRevision(MakePlanetRevision(update.World))
+}
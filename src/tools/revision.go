@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+)
+
+// MakePlanetRevision 由解析后的planet生成版本记录
+func MakePlanetRevision(world *World) configs.ZerotierPlanetRevision {
+	return MakePlanetFile(world, "").HeadRevision()
+}
+
+// IsSameWorld 判断两个planet是否为同一个world的不同版本
+func IsSameWorld(a, b *World) bool {
+	if a.Type != b.Type || a.ID != b.ID {
+		return false
+	}
+	return bytes.Equal(a.UpdatesMustBeSignedBy[:], b.UpdatesMustBeSignedBy[:]) || a.IsUpdateOf(b) || b.IsUpdateOf(a)
+}
+
+// FindPlanetOfWorld 查找同一个world的条目，没有则返回-1
+func FindPlanetOfWorld(cfg *configs.ZerotierSwitcherProfile, world *World) int {
+	for i, p := range cfg.Planets {
+		if p.IsReadOnly() || p.WorldId != world.ID || p.WorldType != world.Type {
+			continue
+		}
+		stored, err := ParsePlanetBase64(p.Data)
+		if err == nil && IsSameWorld(stored, world) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetInstalledRevision 返回当前已安装的版本hash，未安装则返回空
+func GetInstalledRevision(p configs.ZerotierPlanetFile, existingHashStr string) string {
+	for _, rev := range p.AllRevisions() {
+		if CheckIsCurrentPlanet(rev.Data, existingHashStr) {
+			return rev.Hash
+		}
+	}
+	return ""
+}
+
+// StoreWorld 保存planet到配置，同一个world的不同版本归到同一个条目下；返回保存到的条目，以及是否作为已有条目的版本保存
+func StoreWorld(cfg *configs.ZerotierSwitcherProfile, world *World, remark string) (*configs.ZerotierPlanetFile, bool, error) {
+	planet := MakePlanetFile(world, remark)
+	for i := range cfg.Planets {
+		if cfg.Planets[i].FindRevision(planet.Hash) != nil {
+			return &cfg.Planets[i], false, fmt.Errorf("planet file (%s) exists as %s", planet.Hash[:8], cfg.Planets[i].Remark)
+		}
+	}
+	if idx := FindPlanetOfWorld(cfg, world); idx >= 0 {
+		cfg.Planets[idx].AddRevision(planet.HeadRevision())
+		return &cfg.Planets[idx], true, nil
+	}
+	cfg.Planets = append(cfg.Planets, planet)
+	return &cfg.Planets[len(cfg.Planets)-1], false, nil
+}
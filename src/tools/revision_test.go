@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedTestWorld 使用新生成的更新签名密钥重新签名 testdata/worlds 中的world
+func signedTestWorld(t *testing.T, name string) (*World, *C25519KeyPair) {
+	t.Helper()
+	world, _ := readTestWorld(t, name)
+	key, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	world.UpdatesMustBeSignedBy = key.Public
+	if err := world.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return world, key
+}
+
+// nextRevision 同一个world的新版本，timestamp增加delta
+func nextRevision(t *testing.T, world *World, key *C25519KeyPair, delta int64) *World {
+	t.Helper()
+	next := world.Clone()
+	next.Timestamp = uint64(int64(world.Timestamp) + delta)
+	if err := next.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+func TestStoreWorldGroupsRevisions(t *testing.T) {
+	v2, key := signedTestWorld(t, "two-roots.planet")
+	v1 := nextRevision(t, v2, key, -1)
+	v3 := nextRevision(t, v2, key, 1)
+	cfg := newTestProfile(t)
+
+	if _, isRevision, err := StoreWorld(cfg, v2, "team"); err != nil || isRevision {
+		t.Fatalf("first store: revision %v, %v", isRevision, err)
+	}
+	planet, isRevision, err := StoreWorld(cfg, v3, "ignored")
+	if err != nil || !isRevision || planet.Remark != "team" {
+		t.Fatalf("newer revision: %+v, revision %v, %v", planet, isRevision, err)
+	}
+	if _, isRevision, err = StoreWorld(cfg, v1, ""); err != nil || !isRevision {
+		t.Fatalf("older revision: revision %v, %v", isRevision, err)
+	}
+	if _, _, err := StoreWorld(cfg, v2, ""); err == nil || !strings.Contains(err.Error(), "exists as team") {
+		t.Fatalf("got error %v for a stored revision", err)
+	}
+	if len(cfg.Planets) != 1 {
+		t.Fatalf("got %d entries", len(cfg.Planets))
+	}
+
+	// the newest revision is the head, the replaced one records when
+	p := cfg.Planets[0]
+	if p.Hash != MakePlanetFile(v3, "").Hash {
+		t.Fatal("the newest revision is not the head")
+	}
+	var got []uint64
+	for _, rev := range p.AllRevisions() {
+		got = append(got, rev.CreateTime)
+	}
+	if len(got) != 3 || got[0] != v3.Timestamp || got[1] != v2.Timestamp || got[2] != v1.Timestamp {
+		t.Fatalf("got revisions %v", got)
+	}
+	if replaced := p.FindRevision(MakePlanetFile(v2, "").Hash); replaced == nil || replaced.ReplacedTime == 0 {
+		t.Fatalf("replaced revision %+v", replaced)
+	}
+
+	// pinning an older revision selects it for activation
+	cfg.Planets[0].PinnedRevision = MakePlanetFile(v1, "").Hash
+	if active := cfg.Planets[0].ActiveRevision(); active.CreateTime != v1.Timestamp {
+		t.Fatalf("pinned revision not active: %d", active.CreateTime)
+	}
+
+	// the same world ID signed by another key is a different world
+	stranger, _ := signedTestWorld(t, "two-roots.planet")
+	stranger.Timestamp = v3.Timestamp + 1
+	if _, isRevision, err := StoreWorld(cfg, stranger, "other"); err != nil || isRevision || len(cfg.Planets) != 2 {
+		t.Fatalf("other world: revision %v, %d entries, %v", isRevision, len(cfg.Planets), err)
+	}
+}
+
+func TestDetectPlanetUpdate(t *testing.T) {
+	v1, key := signedTestWorld(t, "single-root.planet")
+	v2 := nextRevision(t, v1, key, 1000)
+	forged, _ := signedTestWorld(t, "single-root.planet")
+	forged.Timestamp = v2.Timestamp
+
+	cfg := newTestProfile(t)
+	if _, _, err := StoreWorld(cfg, v1, "team"); err != nil {
+		t.Fatal(err)
+	}
+	home := cfg.ZerotierHome()
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+	install := func(world *World) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(home, "planet"), world.RawData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		installed *World
+		want      bool
+	}{
+		{name: "stored revision", installed: v1},
+		{name: "not signed by the update key", installed: forged},
+		{name: "signed update", installed: v2, want: true},
+	} {
+		install(tt.installed)
+		update, err := DetectPlanetUpdate(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (update != nil) != tt.want {
+			t.Fatalf("%s: got update %+v", tt.name, update)
+		}
+	}
+
+	update, _ := DetectPlanetUpdate(cfg)
+	ApplyPlanetUpdate(cfg, update)
+	p := cfg.Planets[0]
+	if p.CreateTime != v2.Timestamp || len(p.Revisions) != 1 || p.Revisions[0].CreateTime != v1.Timestamp {
+		t.Fatalf("got entry %+v", p)
+	}
+	if installed := GetInstalledRevision(p, GetCurrentPlanetHashFromOS(cfg)); installed != p.Hash {
+		t.Fatalf("installed revision %s, want %s", installed, p.Hash)
+	}
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"golang.org/x/crypto/scrypt"
+	"time"
+)
+
+const (
+	signingKeySaltLen = 16
+	signingKeyScryptN = 1 << 15
+	signingKeyScryptR = 8
+	signingKeyScryptP = 1
+)
+
+// GenerateC25519KeyPair 生成新的C25519密钥对(与mkworld生成的密钥相同)
+func GenerateC25519KeyPair() (*C25519KeyPair, error) {
+	x, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	pair := &C25519KeyPair{}
+	copy(pair.Public[:32], x.PublicKey().Bytes())
+	copy(pair.Public[32:], ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+	copy(pair.Private[:32], x.Bytes())
+	copy(pair.Private[32:], seed)
+	return pair, nil
+}
+
+// signingKeyCipher 由口令派生加密密钥
+func signingKeyCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, signingKeyScryptN, signingKeyScryptR, signingKeyScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSigningKey 使用口令加密密钥对，生成可以保存到配置中的条目
+func EncryptSigningKey(name string, pair *C25519KeyPair, passphrase string) (*configs.ZerotierSigningKey, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	salt := make([]byte, signingKeySaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := signingKeyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// the public key is authenticated with the private key
+	sealed := aead.Seal(nonce, nonce, pair.Private[:], pair.Public[:])
+	return &configs.ZerotierSigningKey{
+		Name:       name,
+		PublicKey:  hex.EncodeToString(pair.Public[:]),
+		PrivateKey: base64.StdEncoding.EncodeToString(sealed),
+		Salt:       hex.EncodeToString(salt),
+		CreateTime: time.Now().Unix(),
+	}, nil
+}
+
+// DecryptSigningKey 使用口令解密保存的密钥对
+func DecryptSigningKey(key *configs.ZerotierSigningKey, passphrase string) (*C25519KeyPair, error) {
+	public, err := hex.DecodeString(key.PublicKey)
+	if err != nil || len(public) != ZT_C25519_PUBLIC_KEY_LEN {
+		return nil, fmt.Errorf("invalid public key of signing key (%s)", key.Name)
+	}
+	salt, err := hex.DecodeString(key.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt of signing key (%s)", key.Name)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of signing key (%s)", key.Name)
+	}
+	aead, err := signingKeyCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid private key of signing key (%s)", key.Name)
+	}
+	private, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], public)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase for signing key (%s)", key.Name)
+	}
+	return ParseC25519KeyPair(append(public, private...))
+}
+
+// FindSigningKeyOfWorld 查找可以为planet签名更新的密钥
+func FindSigningKeyOfWorld(cfg *configs.ZerotierSwitcherProfile, world *World) *configs.ZerotierSigningKey {
+	signer := hex.EncodeToString(world.UpdatesMustBeSignedBy[:])
+	for i := range cfg.SigningKeys {
+		if cfg.SigningKeys[i].PublicKey == signer {
+			return &cfg.SigningKeys[i]
+		}
+	}
+	return nil
+}
+
+// FindPlanetsSignedBy 查找由指定密钥签名更新的planet
+func FindPlanetsSignedBy(cfg *configs.ZerotierSwitcherProfile, key *configs.ZerotierSigningKey) []*configs.ZerotierPlanetFile {
+	var planets []*configs.ZerotierPlanetFile
+	for i := range cfg.Planets {
+		world, err := ParsePlanetBase64(cfg.Planets[i].Data)
+		if err == nil && hex.EncodeToString(world.UpdatesMustBeSignedBy[:]) == key.PublicKey {
+			planets = append(planets, &cfg.Planets[i])
+		}
+	}
+	return planets
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"strings"
+	"testing"
+)
+
+func TestSigningKeyRoundTrip(t *testing.T) {
+	world, pair := signedTestWorld(t, "single-root.planet")
+	key, err := EncryptSigningKey("team", pair, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Name != "team" || key.PublicKey != hex.EncodeToString(pair.Public[:]) {
+		t.Fatalf("got key %+v", key)
+	}
+	if strings.Contains(key.PrivateKey, base64.StdEncoding.EncodeToString(pair.Private[:])) {
+		t.Fatal("the private key is stored in plain text")
+	}
+
+	decrypted, err := DecryptSigningKey(key, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Public != pair.Public || decrypted.Private != pair.Private {
+		t.Fatal("the key pair changed after a round trip")
+	}
+	update := nextRevision(t, world, decrypted, 1)
+	if !update.IsUpdateOf(world) {
+		t.Fatal("the decrypted key does not sign updates")
+	}
+
+	if _, err := EncryptSigningKey("team", pair, ""); err == nil {
+		t.Fatal("encrypted with an empty passphrase")
+	}
+	other, err := EncryptSigningKey("team", pair, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Salt == key.Salt || other.PrivateKey == key.PrivateKey {
+		t.Fatal("salt and nonce are reused")
+	}
+}
+
+func TestDecryptSigningKeyErrors(t *testing.T) {
+	pair, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := EncryptSigningKey("team", pair, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPair, err := GenerateC25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(key.PrivateKey)
+	tamper := func(i int) string {
+		data := append([]byte{}, sealed...)
+		data[i] ^= 1
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name       string
+		edit       func(k *configs.ZerotierSigningKey)
+		passphrase string
+		wantErr    string
+	}{
+		{name: "wrong passphrase", passphrase: "battery staple", wantErr: "wrong passphrase"},
+		{name: "tampered nonce", edit: func(k *configs.ZerotierSigningKey) { k.PrivateKey = tamper(0) }, wantErr: "wrong passphrase"},
+		{name: "tampered ciphertext", edit: func(k *configs.ZerotierSigningKey) { k.PrivateKey = tamper(len(sealed) / 2) }, wantErr: "wrong passphrase"},
+		{name: "tampered tag", edit: func(k *configs.ZerotierSigningKey) { k.PrivateKey = tamper(len(sealed) - 1) }, wantErr: "wrong passphrase"},
+		{name: "truncated", edit: func(k *configs.ZerotierSigningKey) { k.PrivateKey = base64.StdEncoding.EncodeToString(sealed[:8]) }, wantErr: "invalid private key"},
+		{name: "other salt", edit: func(k *configs.ZerotierSigningKey) { k.Salt = strings.Repeat("00", signingKeySaltLen) }, wantErr: "wrong passphrase"},
+		{name: "swapped public key", edit: func(k *configs.ZerotierSigningKey) { k.PublicKey = hex.EncodeToString(otherPair.Public[:]) }, wantErr: "wrong passphrase"},
+		{name: "short public key", edit: func(k *configs.ZerotierSigningKey) { k.PublicKey = k.PublicKey[:16] }, wantErr: "invalid public key"},
+		{name: "bad salt", edit: func(k *configs.ZerotierSigningKey) { k.Salt = "salt" }, wantErr: "invalid salt"},
+		{name: "not base64", edit: func(k *configs.ZerotierSigningKey) { k.PrivateKey = "!" }, wantErr: "invalid private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := *key
+			if tt.edit != nil {
+				tt.edit(&edited)
+			}
+			passphrase := tt.passphrase
+			if passphrase == "" {
+				passphrase = "correct horse"
+			}
+			decrypted, err := DecryptSigningKey(&edited, passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got key %v, error %v, want %q", decrypted, err, tt.wantErr)
+			}
+		})
+	}
+}
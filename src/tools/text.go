@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	TextFormatBase64 = "base64"
+	TextFormatHex    = "hex"
+	TextFormatCArray = "C array"
+)
+
+var cArrayByteRegexp = regexp.MustCompile(`0[xX][0-9a-fA-F]{1,2}\b`)
+
+// decodeCArray 解析 {0x01,0x00,...} 格式的C数组(mkworld的输出)
+func decodeCArray(text string) ([]byte, error) {
+	if start := strings.Index(text, "{"); start >= 0 {
+		text = text[start+1:]
+		if end := strings.Index(text, "}"); end >= 0 {
+			text = text[:end]
+		}
+	}
+	tokens := cArrayByteRegexp.FindAllString(text, -1)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no bytes found")
+	}
+	data := make([]byte, len(tokens))
+	for i, token := range tokens {
+		b, err := strconv.ParseUint(token[2:], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		data[i] = byte(b)
+	}
+	return data, nil
+}
+
+// decodeBase64 兼容带填充/不带填充以及URL安全的base64
+func decodeBase64(text string) ([]byte, error) {
+	var lastErr error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		data, err := encoding.DecodeString(text)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// DecodeWorldText 解析以base64、hex或C数组粘贴的planet，自动识别格式，返回识别出的格式
+func DecodeWorldText(text string) (*World, string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, "", fmt.Errorf("empty text")
+	}
+	compact := strings.Join(strings.Fields(text), "")
+
+	type candidate struct {
+		format string
+		decode func() ([]byte, error)
+	}
+	var candidates []candidate
+	if strings.Contains(strings.ToLower(text), "0x") {
+		candidates = append(candidates, candidate{TextFormatCArray, func() ([]byte, error) { return decodeCArray(text) }})
+	}
+	candidates = append(candidates,
+		candidate{TextFormatHex, func() ([]byte, error) { return hex.DecodeString(strings.ReplaceAll(compact, ":", "")) }},
+		candidate{TextFormatBase64, func() ([]byte, error) { return decodeBase64(compact) }},
+	)
+
+	// the first encoding that decodes to a valid world wins, otherwise report the first decodable one
+	var firstErr error
+	for _, c := range candidates {
+		data, err := c.decode()
+		if err != nil {
+			continue
+		}
+		world, err := ParseWorld(data)
+		if err == nil {
+			return world, c.format, nil
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("decoded as %s but not a valid planet: %v", c.format, err)
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("text is not base64, hex or a C array")
+	}
+	return nil, "", firstErr
+}
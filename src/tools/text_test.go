@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWorldText(t *testing.T) {
+	_, raw := readTestWorld(t, "two-roots.planet")
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	hexText := hex.EncodeToString(raw)
+	var colonHex []string
+	for _, b := range raw {
+		colonHex = append(colonHex, hex.EncodeToString([]byte{b}))
+	}
+	// a C array as copied from Topology.cpp, wrapped over lines
+	cArray := strings.ReplaceAll(FormatCppArray(raw), ",0x", ",\n0x")
+
+	tests := []struct {
+		name       string
+		text       string
+		wantFormat string
+		wantErr    string
+	}{
+		{name: "base64", text: encoded, wantFormat: TextFormatBase64},
+		{name: "wrapped base64", text: "\n" + encoded[:40] + "\n" + encoded[40:] + "\n", wantFormat: TextFormatBase64},
+		{name: "unpadded url base64", text: base64.RawURLEncoding.EncodeToString(raw), wantFormat: TextFormatBase64},
+		{name: "hex", text: hexText, wantFormat: TextFormatHex},
+		{name: "upper case hex with spaces", text: strings.ToUpper(strings.Join(colonHex, " ")), wantFormat: TextFormatHex},
+		{name: "colon separated hex", text: strings.Join(colonHex, ":"), wantFormat: TextFormatHex},
+		{name: "C array", text: cArray, wantFormat: TextFormatCArray},
+		{name: "C array bytes only", text: strings.Join(strings.Split(cArray[strings.Index(cArray, "{")+1:strings.Index(cArray, "}")], "\n"), " "), wantFormat: TextFormatCArray},
+		{name: "empty", text: " \n", wantErr: "empty text"},
+		{name: "not encoded", text: "planet file?", wantErr: "not base64, hex or a C array"},
+		{name: "truncated hex", text: hexText[:len(hexText)/4*2], wantErr: "decoded as hex but not a valid planet"},
+		{name: "other base64 data", text: base64.StdEncoding.EncodeToString([]byte("not a planet")), wantErr: "decoded as base64 but not a valid planet"},
+		{name: "C array of other data", text: "{0x01,0x02}", wantErr: "decoded as C array but not a valid planet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			world, format, err := DecodeWorldText(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.wantFormat {
+				t.Fatalf("decoded as %s, want %s", format, tt.wantFormat)
+			}
+			if !bytes.Equal(world.RawData, raw) {
+				t.Fatal("decoded world differs")
+			}
+		})
+	}
+}
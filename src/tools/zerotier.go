@@ -6,18 +6,25 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
 	"net"
 	"os"
 )
 
 const (
-	ZT_C25519_PUBLIC_KEY_LEN = 64
-	ZT_C25519_SIGNATURE_LEN  = 96
-	ZT_WORLD_MAX_ROOTS       = 4
-	ZT_WORLD_ID_EARTH        = 149604618
-	ZT_INETADDRESS_IPV4      = 0x04
-	ZT_INETADDRESS_IPV6      = 0x06
+	ZT_C25519_PUBLIC_KEY_LEN  = 64
+	ZT_C25519_PRIVATE_KEY_LEN = 64
+	ZT_P384_PUBLIC_KEY_LEN    = 1 + ZT_C25519_PUBLIC_KEY_LEN + 49 // nonce, C25519 public key, compressed P-384 public key
+	ZT_P384_PRIVATE_KEY_LEN   = ZT_C25519_PRIVATE_KEY_LEN + 48
+	ZT_IDENTITY_TYPE_C25519   = 0
+	ZT_IDENTITY_TYPE_P384     = 1
+	ZT_C25519_SIGNATURE_LEN   = 96
+	ZT_WORLD_MAX_ROOTS        = 4
+	ZT_WORLD_ID_EARTH         = 149604618
+	ZT_INETADDRESS_IPV4       = 0x04
+	ZT_INETADDRESS_IPV6       = 0x06
+	ZT_WORLD_TYPE_PLANET      = 1
+	ZT_WORLD_TYPE_MOON        = 127
 )
 
 type World struct {
@@ -28,6 +35,9 @@ type World struct {
 	Signature             [ZT_C25519_SIGNATURE_LEN]byte
 	Roots                 []Root
 	RawData               []byte
+	TrailingData          []byte        // bytes after the end of the world (lenient mode only)
+	Problems              []*ParseError // problems found in lenient mode
+	Layout                []FieldSpan   // position of every parsed field in RawData
 }
 
 type Root struct {
@@ -36,184 +46,132 @@ type Root struct {
 }
 
 type Identity struct {
+	Type      uint8 // 0=C25519/Ed25519, 1=P-384 (NIST P-384 with C25519)
 	Address   [5]byte
-	PublicKey [64]byte
+	PublicKey []byte // 64 bytes for type 0, 114 bytes for type 1
 }
 
 type InetAddress struct {
 	Family uint8
 	IP     net.IP
 	Port   uint16
+	Raw    []byte // serialized form of address types other than IPv4/IPv6
 }
 
-func ParseWorld(data []byte) (*World, error) {
-	if len(data) < 1+8+8+ZT_C25519_PUBLIC_KEY_LEN+ZT_C25519_SIGNATURE_LEN+1 {
-		return nil, fmt.Errorf("data too short to be a valid world file")
-	}
-
-	buf := bytes.NewReader(data)
-	world := &World{
-		RawData: data,
-	}
-
-	// Read type (1 byte)
-	if err := binary.Read(buf, binary.BigEndian, &world.Type); err != nil {
-		return nil, fmt.Errorf("reading type: %w", err)
-	}
-
-	// Read ID (8 bytes)
-	if err := binary.Read(buf, binary.BigEndian, &world.ID); err != nil {
-		return nil, fmt.Errorf("reading id: %w", err)
-	}
-
-	// Read timestamp (8 bytes)
-	if err := binary.Read(buf, binary.BigEndian, &world.Timestamp); err != nil {
-		return nil, fmt.Errorf("reading timestamp: %w", err)
-	}
-
-	// Read update signer public key
-	if _, err := buf.Read(world.UpdatesMustBeSignedBy[:]); err != nil {
-		return nil, fmt.Errorf("reading update signer public key: %w", err)
-	}
-
-	// Read signature
-	if _, err := buf.Read(world.Signature[:]); err != nil {
-		return nil, fmt.Errorf("reading signature: %w", err)
-	}
-
-	// Read number of roots (1 byte)
-	var numRoots uint8
-	if err := binary.Read(buf, binary.BigEndian, &numRoots); err != nil {
-		return nil, fmt.Errorf("reading number of roots: %w", err)
-	}
-
-	if numRoots > ZT_WORLD_MAX_ROOTS {
-		return nil, fmt.Errorf("too many roots (%d > max %d)", numRoots, ZT_WORLD_MAX_ROOTS)
-	}
-
-	// Parse each root
-	for i := 0; i < int(numRoots); i++ {
-		root, err := ParseRoot(buf)
-		if err != nil {
-			return nil, fmt.Errorf("parsing root %d: %w", i, err)
-		}
-		world.Roots = append(world.Roots, *root)
+// IdentityPublicKeyLength 各类型身份的公钥长度
+func IdentityPublicKeyLength(identityType uint8) (int, error) {
+	switch identityType {
+	case ZT_IDENTITY_TYPE_C25519:
+		return ZT_C25519_PUBLIC_KEY_LEN, nil
+	case ZT_IDENTITY_TYPE_P384:
+		return ZT_P384_PUBLIC_KEY_LEN, nil
+	default:
+		return 0, fmt.Errorf("unsupported identity type %d (only 0=C25519 and 1=P-384 are supported)", identityType)
 	}
-
-	return world, nil
 }
 
-func ParseRoot(buf *bytes.Reader) (*Root, error) {
-	root := &Root{}
-
-	// Parse identity
-	identity, err := ParseIdentity(buf)
-	if err != nil {
-		return nil, fmt.Errorf("parsing identity: %w", err)
-	}
-	root.Identity = *identity
-
-	// Read number of endpoints (1 byte)
-	var numEndpoints uint8
-	if err := binary.Read(buf, binary.BigEndian, &numEndpoints); err != nil {
-		return nil, fmt.Errorf("reading number of endpoints: %w", err)
+// String 身份的文本格式 (identity.public)，P-384的公钥使用base32编码
+func (i *Identity) String() string {
+	if i.Type == ZT_IDENTITY_TYPE_P384 {
+		return fmt.Sprintf("%s:%d:%s", i.AddressString(), i.Type, identityBase32.EncodeToString(i.PublicKey))
 	}
+	return fmt.Sprintf("%s:%d:%s", i.AddressString(), i.Type, hex.EncodeToString(i.PublicKey))
+}
 
-	// Parse each endpoint
-	for i := 0; i < int(numEndpoints); i++ {
-		ep, err := ParseInetAddress(buf)
-		if err != nil {
-			return nil, fmt.Errorf("parsing endpoint %d: %w", i, err)
-		}
-		root.StableEndpoints = append(root.StableEndpoints, *ep)
+// TypeName 身份类型名称
+func (i *Identity) TypeName() string {
+	switch i.Type {
+	case ZT_IDENTITY_TYPE_C25519:
+		return "C25519"
+	case ZT_IDENTITY_TYPE_P384:
+		return "P-384"
+	default:
+		return fmt.Sprintf("unknown(%d)", i.Type)
 	}
-
-	return root, nil
 }
 
-func ParseIdentity(buf *bytes.Reader) (*Identity, error) {
-	identity := &Identity{}
+func (i *Identity) AddressString() string {
+	return hex.EncodeToString(i.Address[:])
+}
 
-	// Read address (5 bytes)
-	if _, err := buf.Read(identity.Address[:]); err != nil {
-		return nil, fmt.Errorf("reading address: %w", err)
+func (ia *InetAddress) String() string {
+	if ia.IP == nil {
+		return "invalid"
 	}
 
-	// Read identity type (1 byte)
-	var identityType uint8
-	if err := binary.Read(buf, binary.BigEndian, &identityType); err != nil {
-		return nil, fmt.Errorf("reading identity type: %w", err)
-	}
-	if identityType != 0 {
-		return nil, fmt.Errorf("unsupported identity type %d (only 0=C25519/Ed25519 is supported)", identityType)
+	ipStr := ia.IP.String()
+	if ia.Port != 0 {
+		return fmt.Sprintf("%s:%d", ipStr, ia.Port)
 	}
+	return ipStr
+}
 
-	// Read public key (64 bytes)
-	if _, err := buf.Read(identity.PublicKey[:]); err != nil {
-		return nil, fmt.Errorf("reading public key: %w", err)
+// Serialize 按照ZeroTier的格式序列化，forSign为true时生成用于签名的内容
+func (w World) Serialize(forSign bool) []byte {
+	buf := &bytes.Buffer{}
+	if forSign {
+		_ = binary.Write(buf, binary.BigEndian, uint64(0x7f7f7f7f7f7f7f7f))
+	}
+	buf.WriteByte(w.Type)
+	_ = binary.Write(buf, binary.BigEndian, w.ID)
+	_ = binary.Write(buf, binary.BigEndian, w.Timestamp)
+	buf.Write(w.UpdatesMustBeSignedBy[:])
+	if !forSign {
+		buf.Write(w.Signature[:])
+	}
+	buf.WriteByte(uint8(len(w.Roots)))
+	for _, root := range w.Roots {
+		root.Identity.Serialize(buf)
+		buf.WriteByte(uint8(len(root.StableEndpoints)))
+		for _, ep := range root.StableEndpoints {
+			ep.Serialize(buf)
+		}
 	}
-
-	// Skip private key if present (we don't need it)
-	var privateKeyLen uint8
-	if err := binary.Read(buf, binary.BigEndian, &privateKeyLen); err != nil {
-		return nil, fmt.Errorf("reading private key length: %w", err)
+	if w.Type == ZT_WORLD_TYPE_MOON {
+		// no attached dictionary
+		_ = binary.Write(buf, binary.BigEndian, uint16(0))
 	}
-	if privateKeyLen > 0 {
-		if _, err := buf.Seek(int64(privateKeyLen), 1); err != nil {
-			return nil, fmt.Errorf("skipping private key: %w", err)
-		}
+	if forSign {
+		_ = binary.Write(buf, binary.BigEndian, uint64(0xf7f7f7f7f7f7f7f7))
 	}
+	return buf.Bytes()
+}
 
-	return identity, nil
+// VerifySignature 校验planet是否由指定的密钥签名
+func (w World) VerifySignature(signer [ZT_C25519_PUBLIC_KEY_LEN]byte) bool {
+	return C25519Verify(signer, w.Serialize(true), w.Signature)
 }
 
-func ParseInetAddress(buf *bytes.Reader) (*InetAddress, error) {
-	addr := &InetAddress{}
+// IsUpdateOf 判断是否为旧planet的合法更新(同一个world，时间更新，并由旧planet指定的密钥签名)
+func (w World) IsUpdateOf(old *World) bool {
+	return w.Type == old.Type && w.ID == old.ID && w.Timestamp > old.Timestamp && w.VerifySignature(old.UpdatesMustBeSignedBy)
+}
 
-	// Read address family (1 byte)
-	if err := binary.Read(buf, binary.BigEndian, &addr.Family); err != nil {
-		return nil, fmt.Errorf("reading address family: %w", err)
-	}
+// Serialize 序列化身份公钥(不包含私钥)
+func (i *Identity) Serialize(buf *bytes.Buffer) {
+	buf.Write(i.Address[:])
+	buf.WriteByte(i.Type)
+	buf.Write(i.PublicKey)
+	buf.WriteByte(0)
+}
 
-	switch addr.Family {
+func (ia *InetAddress) Serialize(buf *bytes.Buffer) {
+	switch ia.Family {
 	case ZT_INETADDRESS_IPV4:
-		var ip [4]byte
-		if _, err := buf.Read(ip[:]); err != nil {
-			return nil, fmt.Errorf("reading IPv4 address: %w", err)
-		}
-		addr.IP = net.IPv4(ip[0], ip[1], ip[2], ip[3])
+		buf.WriteByte(ZT_INETADDRESS_IPV4)
+		buf.Write(ia.IP.To4())
 	case ZT_INETADDRESS_IPV6:
-		var ip [16]byte
-		if _, err := buf.Read(ip[:]); err != nil {
-			return nil, fmt.Errorf("reading IPv6 address: %w", err)
-		}
-		addr.IP = net.IP(ip[:])
+		buf.WriteByte(ZT_INETADDRESS_IPV6)
+		buf.Write(ia.IP.To16())
 	default:
-		return nil, fmt.Errorf("unsupported address family %d", addr.Family)
-	}
-
-	// Read port (2 bytes)
-	if err := binary.Read(buf, binary.BigEndian, &addr.Port); err != nil {
-		return nil, fmt.Errorf("reading port: %w", err)
-	}
-
-	return addr, nil
-}
-
-func (i *Identity) String() string {
-	return fmt.Sprintf("%s:%s", hex.EncodeToString(i.Address[:]), hex.EncodeToString(i.PublicKey[:]))
-}
-
-func (ia *InetAddress) String() string {
-	if ia.IP == nil {
-		return "invalid"
-	}
-
-	ipStr := ia.IP.String()
-	if ia.Port != 0 {
-		return fmt.Sprintf("%s:%d", ipStr, ia.Port)
+		if ia.Raw != nil {
+			buf.Write(ia.Raw)
+		} else {
+			buf.WriteByte(0)
+		}
+		return
 	}
-	return ipStr
+	_ = binary.Write(buf, binary.BigEndian, ia.Port)
 }
 
 func (w World) ToBase64() string {
@@ -236,38 +194,27 @@ func ParsePlanetBase64(b64encText string) (*World, error) {
 	return ParseWorld(fileContent)
 }
 
-func demo() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: ztplanet <world.bin>")
-		os.Exit(1)
-	}
-
-	filename := os.Args[1]
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
-		os.Exit(1)
-	}
-
-	world, err := ParseWorld(data)
-	if err != nil {
-		fmt.Printf("Error parsing world file: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Printf("ZeroTier Planet Information:\n")
-	fmt.Printf("  ID: %d\n", world.ID)
-	fmt.Printf("  Type: %d (1=Planet, 127=Moon)\n", world.Type)
-	fmt.Printf("  Timestamp: %d\n", world.Timestamp)
-	fmt.Printf("  Update Signer Public Key: %s\n", hex.EncodeToString(world.UpdatesMustBeSignedBy[:]))
-	fmt.Printf("  Signature: %s...\n", hex.EncodeToString(world.Signature[:16]))
-	fmt.Printf("  Number of Roots: %d\n", len(world.Roots))
-
-	for i, root := range world.Roots {
-		fmt.Printf("\nRoot Server %d:\n", i+1)
-		fmt.Printf("  Identity: %s\n", root.Identity.String())
-		for j, ep := range root.StableEndpoints {
-			fmt.Printf("  Endpoint %d: %s\n", j+1, ep.String())
+// MakePlanetFile 由解析后的planet生成配置条目
+func MakePlanetFile(world *World, remark string) configs.ZerotierPlanetFile {
+	var root Root
+	var ep InetAddress
+	if len(world.Roots) > 0 {
+		root = world.Roots[0]
+		if len(root.StableEndpoints) > 0 {
+			ep = root.StableEndpoints[0]
 		}
 	}
+	if remark == "" {
+		remark = ep.String()
+	}
+	return configs.ZerotierPlanetFile{
+		Hash:         configs.PlanetHash(world.RawData),
+		Remark:       remark,
+		Data:         world.ToBase64(),
+		CreateTime:   world.Timestamp,
+		WorldId:      world.ID,
+		WorldType:    world.Type,
+		RootIdentity: root.Identity.String(),
+		RootEndpoint: ep.String(),
+	}
 }
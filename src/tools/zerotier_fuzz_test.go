@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// worldCorpus testdata/worlds 中的planet和moon文件，以及内置的Earth planet
+func worldCorpus(tb testing.TB) []string {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "worlds", "*"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var corpus []string
+	for _, file := range files {
+		if filepath.Ext(file) == ".planet" || filepath.Ext(file) == ".moon" {
+			corpus = append(corpus, file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join("earth", "planet")); err == nil {
+		corpus = append(corpus, filepath.Join("earth", "planet"))
+	}
+	return corpus
+}
+
+// TestWorldCorpusRoundTrip 语料中的文件解析后应能逐字节还原
+func TestWorldCorpusRoundTrip(t *testing.T) {
+	for _, file := range worldCorpus(t) {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			world, err := ParseWorld(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if serialized := world.Serialize(false); !bytes.Equal(serialized, data) {
+				t.Fatalf("serialized %d bytes differ from the %d bytes read", len(serialized), len(data))
+			}
+			if len(world.TrailingData) > 0 {
+				t.Fatalf("%d bytes of trailing data", len(world.TrailingData))
+			}
+			// genmoon names moons after their world ID
+			if name := fmt.Sprintf("%016x.moon", world.ID); world.Type == ZT_WORLD_TYPE_MOON && filepath.Base(file) != name {
+				t.Fatalf("moon stored as %s, ZeroTier names it %s", filepath.Base(file), name)
+			}
+		})
+	}
+}
+
+// FuzzParseWorld 解析任意数据不应崩溃，严格解析成功的数据序列化后应能得到相同的结果
+func FuzzParseWorld(f *testing.F) {
+	for _, file := range worldCorpus(f) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+		// truncated and padded variants
+		f.Add(data[:len(data)/2])
+		f.Add(append(append([]byte{}, data...), 0xde, 0xad))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lenient, err := ParseWorldWithOptions(data, ParseOptions{Lenient: true})
+		if err != nil || lenient == nil {
+			t.Fatalf("lenient parse must not fail: %v", err)
+		}
+
+		world, err := ParseWorld(data)
+		if err != nil {
+			if _, ok := err.(*ParseError); !ok {
+				t.Fatalf("unexpected error type %T: %v", err, err)
+			}
+			return
+		}
+		if len(lenient.Problems) > 0 {
+			t.Fatalf("lenient parse reported problems for valid data: %v", lenient.Problems[0])
+		}
+
+		serialized := world.Serialize(false)
+		again, err := ParseWorld(serialized)
+		if err != nil {
+			t.Fatalf("serialized world does not parse: %v", err)
+		}
+		if !bytes.Equal(again.Serialize(false), serialized) {
+			t.Fatalf("serialization is not stable")
+		}
+	})
+}
@@ -2,13 +2,17 @@ package views
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/LanceLRQ/zerotier-switcher/src/configs"
 	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"os"
@@ -22,6 +26,7 @@ var planetListStyle = lipgloss.NewStyle().Margin(1, 2)
 var filePickerStyle = lipgloss.NewStyle().Margin(4, 2)
 var filePickerErrorStyle = lipgloss.NewStyle().Background(lipgloss.Color("9")).Foreground(lipgloss.Color("15"))
 var filePickerSuccessStyle = lipgloss.NewStyle().Background(lipgloss.Color("10")).Foreground(lipgloss.Color("15"))
+var warningStyle = lipgloss.NewStyle().Background(lipgloss.Color("11")).Foreground(lipgloss.Color("0"))
 var activateTitleStyle = lipgloss.NewStyle().Background(lipgloss.Color("12")).Foreground(lipgloss.Color("15"))
 var progressBarPadding = 2
 var progressBarMaxWidth = 80
@@ -33,8 +38,25 @@ type progressMsg struct {
 	error bool
 }
 
+type clipboardMsg struct {
+	text string
+	err  error
+}
+
+// watchInterval 检查配置文件和当前planet文件是否被修改的间隔
+var watchInterval = 2 * time.Second
+
+type watchMsg struct{}
+
+type catalogSyncMsg struct {
+	snapshots map[string]*tools.CatalogSnapshot
+	errors    []string
+}
+
 const MaxRemarkLength = 64
 const MaxAutoJoinNetworkLength = 64
+const MaxTagsLength = 128
+const MaxEditInputLength = 512
 
 type AppViewModel struct {
 	IsRunAsRoot        bool
@@ -45,22 +67,47 @@ type AppViewModel struct {
 	planetFile         *configs.ZerotierPlanetFile
 	planetList         list.Model
 	actionList         list.Model
+	revisionList       list.Model
+	exportList         list.Model
+	hexView            viewport.Model
+	editList           list.Model
+	editWorld          *tools.World
+	editModified       bool
+	editInput          textinput.Model
+	editInputMode      string // root, endpoint, key or passphrase
+	hexViewTitle       string
+	hexViewBack        string
 	filePickerView     filepicker.Model
+	importPickerView   filepicker.Model
+	mergeList          list.Model
+	backupList         list.Model
+	settingsList       list.Model
+	settingInput       textinput.Model
+	editingSetting     *configs.ProfileSetting
+	mergePlan          *tools.MergePlan
 	errorMessage       string
 	successMessage     string
+	warningMessage     string
 	filePickerSelected string
 	remarkInput        textinput.Model
 	autoJoinInput      textinput.Model
+	tagsInput          textinput.Model
+	addTextInput       textarea.Model
 	progressBar        progress.Model
 	activateStep       int
 	activateLock       bool
 	activateStepDesc   string
 	confirmCursor      int
+	planetUpdate       *tools.PlanetUpdate
+	profileChangedBack string       // screen to return to when keeping the local profile
+	installedHash      string       // hash of the installed planet file when last checked
+	installedPlanet    *tools.World // installed planet compared on the activate and diff screens
+	installedPlanetErr error        // why the installed planet could not be read
 	currentWindowSize  tea.WindowSizeMsg
 }
 
 func (m AppViewModel) Init() tea.Cmd {
-	return nil
+	return watchFiles()
 }
 
 func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -74,40 +121,107 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.successMessage != "" {
 			m.successMessage = ""
 		}
+		if m.warningMessage != "" {
+			m.warningMessage = ""
+		}
+		if m.screen != "profile_changed" && m.screen != "activate_process" && msg.String() != "ctrl+c" && m.config.ChangedOnDisk() {
+			m.profileChangedBack = m.screen
+			m.confirmCursor = 0
+			m.screen = "profile_changed"
+			return m, nil
+		}
 		switch msg.String() {
 		case "down", "w", "j":
-			if m.screen == "delete_confirm" {
+			if m.screen == "delete_confirm" || m.screen == "planet_update" || m.screen == "profile_changed" {
 				m.confirmCursor++
 				if m.confirmCursor >= 2 {
 					m.confirmCursor = 0
 				}
 			}
 		case "up", "s", "k":
-			if m.screen == "delete_confirm" {
+			if m.screen == "delete_confirm" || m.screen == "planet_update" || m.screen == "profile_changed" {
 				m.confirmCursor--
 				if m.confirmCursor < 0 {
 					m.confirmCursor = 1
 				}
 			}
 
+		case "p":
+			if m.screen == "revisions" {
+				rItem, ok := m.revisionList.SelectedItem().(RevisionItem)
+				if ok {
+					if m.planetFile.PinnedRevision == rItem.Revision.Hash {
+						m.planetFile.PinnedRevision = ""
+						m.successMessage = "Unpinned, the latest revision will be activated"
+					} else {
+						m.planetFile.PinnedRevision = rItem.Revision.Hash
+						m.successMessage = fmt.Sprintf("Pinned revision %d for activation", rItem.Revision.CreateTime)
+					}
+					if err := m.savePlanetChange(); err != nil {
+						m.successMessage = ""
+						m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+						break
+					}
+					m.refreshPlanetItems()
+				}
+			}
+		case "a", "e", "x", "[", "]", "t", "ctrl+s":
+			if m.screen == "edit_planet" {
+				m.handleEditorKey(msg.String())
+				return m, textinput.Blink
+			}
+			if m.screen == "import_merge" && msg.String() == "ctrl+s" {
+				m.applyMergePlan()
+				return m, nil
+			}
+			if m.screen == "add_text" && msg.String() == "ctrl+s" {
+				m.addPlanetFromText(m.addTextInput.Value())
+				return m, nil
+			}
+			if m.screen == "settings" && msg.String() == "x" {
+				if item, ok := m.settingsList.SelectedItem().(SettingItem); ok {
+					m.applySetting(item.Setting, "")
+				}
+				return m, nil
+			}
+		case "ctrl+v":
+			if m.screen == "add_text" {
+				return m, readClipboard(m.Program)
+			}
 		case "backspace":
 			switch m.screen {
 			case "action":
 				m.screen = "list"
-			case "view_planet", "delete_confirm":
+			case "view_planet", "delete_confirm", "diff_installed":
 				m.screen = "action"
 			}
 		case "esc":
 			switch m.screen {
 			case "list":
 				return m, tea.Quit
-			case "action", "file_picker", "import_tip":
+			case "action", "file_picker", "import_picker", "planet_update", "add_text", "restore", "settings":
 				m.screen = "list"
-			case "activate", "view_planet", "delete_confirm", "rename", "auto_join":
+			case "setting_input":
+				m.screen = "settings"
+			case "import_merge":
+				m.mergePlan = nil
+				m.screen = "import_picker"
+			case "profile_changed":
+				m.keepLocalProfile()
+			case "activate", "view_planet", "delete_confirm", "rename", "auto_join", "tags", "revisions", "diff_installed", "export":
+				m.screen = "action"
+			case "revision_diff":
+				m.screen = "revisions"
+			case "hex_view":
+				m.screen = m.hexViewBack
+			case "edit_planet":
+				m.editWorld = nil
 				m.screen = "action"
+			case "edit_input":
+				m.screen = "edit_planet"
 			case "activate_process":
 				if !m.activateLock {
-					m.planetList.SetItems(RenderPlanetListItem(m.config.Planets))
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
 					m.screen = "list"
 				}
 			}
@@ -133,8 +247,32 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.errorMessage = err.Error()
 						}
 						m.successMessage = fmt.Sprintf("Saved to current folder")
+					} else if p.Id == "add_text" {
+						m.addTextInput.Reset()
+						m.screen = "add_text"
+						return m, textarea.Blink
 					} else if p.Id == "import" {
-						m.screen = "import_tip"
+						m.screen = "import_picker"
+						return m, m.importPickerView.Init()
+					} else if p.Id == "reset_default" {
+						m.openResetToDefault()
+					} else if p.Id == "settings" {
+						m.settingsList.SetItems(RenderSettingsListItem(m.config))
+						m.screen = "settings"
+					} else if p.Id == "restore" {
+						backups, err := m.config.ListBackups(true)
+						if err != nil {
+							m.errorMessage = err.Error()
+						} else if len(backups) == 0 {
+							m.errorMessage = "No backups yet, the profile is backed up before every change"
+						} else {
+							m.backupList.SetItems(RenderBackupListItem(backups))
+							m.backupList.Select(0)
+							m.screen = "restore"
+						}
+					} else if p.Id == "catalog_sync" {
+						m.successMessage = "Syncing catalogs..."
+						return m, fetchCatalogs(m.config.Catalogs)
 					} else {
 						m.planetFile = p.Planet
 						m.currentPlanetItem = p
@@ -156,21 +294,32 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					world, err := m.parsePlanetFile()
 					if err != nil {
 						m.errorMessage = fmt.Sprintf("Not a valid planet file: %s", err.Error())
+						// show where the file deviates from the format
+						if data, rErr := os.ReadFile(sPath); rErr == nil {
+							broken, _ := tools.ParseWorldWithOptions(data, tools.ParseOptions{Lenient: true})
+							m.openHexView(broken, filepath.Base(sPath), "file_picker")
+						}
 						break
 					}
-					planet := m.makePlanetFileItem(world, filepath.Base(sPath))
-					if m.isPlanetFileExists(planet) {
-						m.errorMessage = fmt.Sprintf("Planet file (%s) exists", planet.RootIdentity[:8])
+					// 同一个world的不同版本归到同一个条目下
+					planet, isRevision, err := tools.StoreWorld(m.config, world, filepath.Base(sPath))
+					if err != nil {
+						m.errorMessage = err.Error()
 						break
 					}
-					m.config.Planets = append(m.config.Planets, *planet)
+					if isRevision {
+						m.successMessage = fmt.Sprintf("Added as a revision of %s", planet.Remark)
+					}
+					if issues := tools.LintWorld(world, false); len(issues) > 0 {
+						m.warningMessage = RenderLintIssues(issues)
+					}
 					err = m.config.WriteAppConfig()
 					if err != nil {
 						m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
 						break
 					}
 					// rebuild list
-					m.planetList.SetItems(RenderPlanetListItem(m.config.Planets))
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
 					// Back to list
 					m.screen = "list"
 					m.errorMessage = ""
@@ -181,6 +330,7 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if ok {
 					switch aItem.Id {
 					case "activate":
+						m.loadInstalledPlanet()
 						m.screen = "activate"
 						return m, nil
 					case "rename":
@@ -193,9 +343,48 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.autoJoinInput.SetValue(m.planetFile.AutoJoinNetwork)
 						m.autoJoinInput.SetCursor(0)
 						return m, textinput.Blink
+					case "tags":
+						m.screen = "tags"
+						m.tagsInput.SetValue(strings.Join(m.planetFile.Tags, ", "))
+						m.tagsInput.SetCursor(0)
+						return m, textinput.Blink
 					case "view":
 						m.screen = "view_planet"
 						return m, nil
+					case "export":
+						m.exportList.ResetSelected()
+						m.screen = "export"
+						return m, nil
+					case "hex":
+						world, err := tools.ParsePlanetBase64(m.planetFile.ActiveRevision().Data)
+						if err != nil {
+							m.errorMessage = fmt.Sprintf("Parse planet error: %s", err.Error())
+							break
+						}
+						m.openHexView(world, m.planetFile.Remark, "action")
+						return m, nil
+					case "diff":
+						m.loadInstalledPlanet()
+						m.screen = "diff_installed"
+						return m, nil
+					case "edit":
+						world, err := tools.ParsePlanetBase64(m.planetFile.Data)
+						if err != nil {
+							m.errorMessage = fmt.Sprintf("Parse planet error: %s", err.Error())
+							break
+						}
+						m.editWorld = world.Clone()
+						m.editModified = false
+						m.refreshEditorItems(0, -1)
+						m.editList.ResetSelected()
+						m.screen = "edit_planet"
+						return m, nil
+					case "revisions":
+						m.revisionList.Title = m.getActionPageTitle()
+						m.revisionList.SetItems(RenderRevisionListItem(*m.planetFile, tools.GetInstalledRevision(*m.planetFile, tools.GetCurrentPlanetHashFromOS(m.config))))
+						m.revisionList.ResetSelected()
+						m.screen = "revisions"
+						return m, nil
 					case "delete":
 						if len(m.config.Planets) <= 1 {
 							m.errorMessage = fmt.Sprintf("The last planet file cannot be delete. ")
@@ -205,7 +394,7 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.screen = "delete_confirm"
 					}
 				}
-			case "rename", "auto_join":
+			case "rename", "auto_join", "tags":
 				if m.screen == "rename" {
 					newVal := m.remarkInput.Value()
 					if newVal == "" {
@@ -215,6 +404,14 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else if m.screen == "auto_join" {
 					newVal := m.autoJoinInput.Value()
 					m.planetFile.AutoJoinNetwork = newVal
+				} else if m.screen == "tags" {
+					var tags []string
+					for _, tag := range strings.Split(m.tagsInput.Value(), ",") {
+						if tag = strings.TrimSpace(tag); tag != "" {
+							tags = append(tags, tag)
+						}
+					}
+					m.planetFile.Tags = tags
 				}
 				err := m.savePlanetChange()
 				if err != nil {
@@ -223,9 +420,75 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.actionList.Title = m.getActionPageTitle()
 				// rebuild list
-				m.planetList.SetItems(RenderPlanetListItem(m.config.Planets))
+				m.planetList.SetItems(RenderPlanetListItem(m.config))
 				m.screen = "action"
 				m.errorMessage = ""
+			case "edit_input":
+				m.applyEditInput()
+				return m, nil
+			case "settings":
+				item, ok := m.settingsList.SelectedItem().(SettingItem)
+				if !ok {
+					return m, nil
+				}
+				if len(item.Setting.Choices) > 0 {
+					// cycle through the fixed values
+					next := item.Setting.Choices[0]
+					for i, choice := range item.Setting.Choices {
+						if choice == item.Value && i+1 < len(item.Setting.Choices) {
+							next = item.Setting.Choices[i+1]
+						}
+					}
+					m.applySetting(item.Setting, next)
+					return m, nil
+				}
+				m.editingSetting = item.Setting
+				m.settingInput.Placeholder = item.Setting.Default(m.config)
+				if item.Source.Source == configs.SourceSystem {
+					m.settingInput.Placeholder = item.Value
+				}
+				m.settingInput.SetValue(item.Setting.Get(m.config))
+				m.settingInput.CursorEnd()
+				m.screen = "setting_input"
+				return m, textinput.Blink
+			case "setting_input":
+				if m.applySetting(m.editingSetting, strings.TrimSpace(m.settingInput.Value())) {
+					m.screen = "settings"
+				}
+				return m, nil
+			case "export":
+				if item, ok := m.exportList.SelectedItem().(ActionItem); ok {
+					fileName, err := m.exportPlanet(item.Id)
+					if err != nil {
+						m.errorMessage = err.Error()
+						break
+					}
+					m.successMessage = fmt.Sprintf("Exported to %s", fileName)
+				}
+				return m, nil
+			case "revisions":
+				if _, ok := m.revisionList.SelectedItem().(RevisionItem); ok {
+					m.screen = "revision_diff"
+				}
+				return m, nil
+			case "profile_changed":
+				if m.confirmCursor == 0 {
+					m.reloadProfile()
+				} else {
+					m.keepLocalProfile()
+				}
+			case "planet_update":
+				if m.confirmCursor == 0 {
+					tools.ApplyPlanetUpdate(m.config, m.planetUpdate)
+					if err := m.config.WriteAppConfig(); err != nil {
+						m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+						break
+					}
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
+					m.successMessage = "Planet file updated, the previous revision is kept"
+				}
+				m.planetUpdate = nil
+				m.screen = "list"
 			case "delete_confirm":
 				if m.confirmCursor == 0 {
 					err := m.removePlanet()
@@ -234,7 +497,7 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						break
 					}
 					// rebuild list
-					m.planetList.SetItems(RenderPlanetListItem(m.config.Planets))
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
 					m.screen = "list"
 					m.planetFile = nil
 					m.errorMessage = ""
@@ -252,18 +515,23 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.activateLock = true
 				go func() {
 					currentStep := 0
-					if err := tools.ReplacePlanetAndJoinNetwork(
-						m.planetFile.Data,
-						m.planetFile.AutoJoinNetwork,
-						func(step int, desc string) {
-							currentStep = step
-							m.Program.Send(progressMsg{
-								step:  step,
-								desc:  desc,
-								error: false,
-							})
-						},
-					); err != nil {
+					callback := func(step int, desc string) {
+						currentStep = step
+						m.Program.Send(progressMsg{
+							step:  step,
+							desc:  desc,
+							error: false,
+						})
+					}
+					var err error
+					if m.planetFile.WorldType == tools.ZT_WORLD_TYPE_MOON {
+						// moons are installed next to the planet instead of replacing it
+						err = tools.InstallMoon(m.config, m.planetFile.ActiveRevision().Data, callback)
+					} else if _, err = tools.PreserveOriginalPlanet(m.config); err == nil {
+						// never lose the original planet file
+						err = tools.ReplacePlanetAndJoinNetwork(m.config, m.planetFile.ActiveRevision().Data, m.planetFile.AutoJoinNetwork, callback)
+					}
+					if err != nil {
 						m.Program.Send(progressMsg{
 							step:  currentStep,
 							desc:  filePickerErrorStyle.Width(m.currentWindowSize.Width).Render(err.Error()),
@@ -271,9 +539,34 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						})
 					}
 				}()
+			case "import_picker":
+				m.importPickerView, cmd = m.importPickerView.Update(msg)
+				if didSelect, sPath := m.importPickerView.DidSelectFile(msg); didSelect {
+					m.openMergePlan(sPath)
+				}
+				return m, cmd
+			case "restore":
+				if item, ok := m.backupList.SelectedItem().(BackupItem); ok {
+					if item.Backup.Err != nil {
+						m.errorMessage = item.Backup.Err.Error()
+						break
+					}
+					if err := m.config.RestoreBackup(item.Backup); err != nil {
+						m.errorMessage = err.Error()
+						break
+					}
+					m.successMessage = fmt.Sprintf("Restored the backup of %s, the previous profile was backed up", item.Backup.Time.Format(time.DateTime))
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
+					m.screen = "list"
+				}
+			case "import_merge":
+				if item, ok := m.mergeList.SelectedItem().(MergeConflictItem); ok {
+					item.Conflict.UseBackup = !item.Conflict.UseBackup
+					m.mergeList.SetItems(RenderMergeListItem(m.mergePlan))
+				}
 			case "activate_process":
 				if !m.activateLock {
-					m.planetList.SetItems(RenderPlanetListItem(m.config.Planets))
+					m.planetList.SetItems(RenderPlanetListItem(m.config))
 					m.screen = "list"
 				}
 			}
@@ -284,11 +577,53 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		_, fv := filePickerStyle.GetFrameSize()
 		m.planetList.SetSize(msg.Width-h, msg.Height-v)
 		m.actionList.SetSize(msg.Width-h, msg.Height-v)
+		m.revisionList.SetSize(msg.Width-h, msg.Height-v)
+		m.exportList.SetSize(msg.Width-h, msg.Height-v)
+		m.editList.SetSize(msg.Width-h, msg.Height-v)
+		m.mergeList.SetSize(msg.Width-h, msg.Height-v-4)
+		m.backupList.SetSize(msg.Width-h, msg.Height-v)
+		m.settingsList.SetSize(msg.Width-h, msg.Height-v)
+		m.addTextInput.SetWidth(msg.Width - 4)
+		m.addTextInput.SetHeight(msg.Height - 10)
+		m.hexView.Width = msg.Width
+		m.hexView.Height = msg.Height - 4
 		m.filePickerView.SetHeight(msg.Height - fv)
+		m.importPickerView.SetHeight(msg.Height - fv)
 		m.progressBar.Width = msg.Width - progressBarPadding*2 - 4
 		if m.progressBar.Width > progressBarMaxWidth {
 			m.progressBar.Width = progressBarMaxWidth
 		}
+	case clipboardMsg:
+		if msg.err != nil {
+			m.errorMessage = msg.err.Error()
+			return m, nil
+		}
+		m.addTextInput.InsertString(msg.text)
+		return m, nil
+	case catalogSyncMsg:
+		var results []string
+		for _, item := range m.config.Catalogs {
+			if snapshot, ok := msg.snapshots[item.Name]; ok {
+				result := tools.ApplyCatalog(m.config, item.Name, snapshot)
+				results = append(results, fmt.Sprintf("%s: %s", item.Name, result))
+			}
+		}
+		m.successMessage = ""
+		if len(results) > 0 {
+			if err := m.config.WriteAppConfig(); err != nil {
+				msg.errors = append(msg.errors, fmt.Sprintf("Save profile error: %s", err.Error()))
+			} else {
+				m.successMessage = strings.Join(results, "\n")
+			}
+		}
+		if len(msg.errors) > 0 {
+			m.errorMessage = strings.Join(msg.errors, "\n")
+		}
+		m.planetList.SetItems(RenderPlanetListItem(m.config))
+		return m, nil
+	case watchMsg:
+		m.checkFilesChanged()
+		return m, watchFiles()
 	case progressMsg:
 		m.activateStep = msg.step
 		m.activateStepDesc = msg.desc
@@ -310,12 +645,36 @@ func (m AppViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.planetList, cmd = m.planetList.Update(msg)
 	case "action":
 		m.actionList, cmd = m.actionList.Update(msg)
+	case "revisions":
+		m.revisionList, cmd = m.revisionList.Update(msg)
+	case "export":
+		m.exportList, cmd = m.exportList.Update(msg)
+	case "hex_view":
+		m.hexView, cmd = m.hexView.Update(msg)
+	case "edit_planet":
+		m.editList, cmd = m.editList.Update(msg)
+	case "edit_input":
+		m.editInput, cmd = m.editInput.Update(msg)
+	case "add_text":
+		m.addTextInput, cmd = m.addTextInput.Update(msg)
 	case "file_picker":
 		m.filePickerView, cmd = m.filePickerView.Update(msg)
+	case "import_picker":
+		m.importPickerView, cmd = m.importPickerView.Update(msg)
+	case "import_merge":
+		m.mergeList, cmd = m.mergeList.Update(msg)
+	case "restore":
+		m.backupList, cmd = m.backupList.Update(msg)
+	case "settings":
+		m.settingsList, cmd = m.settingsList.Update(msg)
+	case "setting_input":
+		m.settingInput, cmd = m.settingInput.Update(msg)
 	case "rename":
 		m.remarkInput, cmd = m.remarkInput.Update(msg)
 	case "auto_join":
 		m.autoJoinInput, cmd = m.autoJoinInput.Update(msg)
+	case "tags":
+		m.tagsInput, cmd = m.tagsInput.Update(msg)
 	}
 
 	return m, cmd
@@ -331,9 +690,53 @@ func (m AppViewModel) View() string {
 		s.WriteString(m.planetList.View())
 	case "action":
 		s.WriteString(m.actionList.View())
+	case "revisions":
+		s.WriteString(m.revisionList.View())
+	case "export":
+		s.WriteString(m.exportList.View())
+	case "revision_diff":
+		s.WriteString(m.renderRevisionDiffView() + "\n\n(ESC to back)")
+	case "diff_installed":
+		s.WriteString(m.renderInstalledDiffView() + "\n\n(ESC to back)")
+	case "add_text":
+		s.WriteString(fmt.Sprintf(
+			"Paste a planet as base64, hex or C array:\n\n%s\n\n%s\n\n",
+			m.addTextInput.View(),
+			"(ctrl+s to add, ctrl+v to paste from the clipboard, ESC to back)",
+		))
+	case "edit_planet":
+		s.WriteString(m.editList.View())
+	case "edit_input":
+		s.WriteString(fmt.Sprintf(
+			"%s\n\n%s\n\n%s\n\n",
+			m.getEditInputPrompt(),
+			m.editInput.View(),
+			"(ESC to back)",
+		) + "\n")
+	case "hex_view":
+		s.WriteString(activateTitleStyle.Render(m.hexViewTitle) + "\n\n")
+		s.WriteString(m.hexView.View())
+		s.WriteString(fmt.Sprintf("\n\n(%3.f%%, ↑/↓ to scroll, ESC to back)", m.hexView.ScrollPercent()*100))
 	case "file_picker":
 		s.WriteString("\n Please pick a zerotier planet file.")
 		s.WriteString("\n\n" + m.filePickerView.View() + "\n")
+	case "import_picker":
+		s.WriteString("\n Please pick a backup file to merge into the list.")
+		s.WriteString("\n\n" + m.importPickerView.View() + "\n")
+	case "import_merge":
+		s.WriteString(m.renderMergePlanView())
+	case "restore":
+		s.WriteString(m.backupList.View())
+	case "settings":
+		s.WriteString(m.settingsList.View())
+	case "setting_input":
+		s.WriteString(fmt.Sprintf(
+			"%s (%s)\n\n%s\n\n%s\n\n",
+			m.editingSetting.Name,
+			m.editingSetting.Desc,
+			m.settingInput.View(),
+			"(empty for the default, ESC to back)",
+		) + "\n")
 
 	case "rename":
 		s.WriteString(fmt.Sprintf(
@@ -351,10 +754,22 @@ func (m AppViewModel) View() string {
 			MaxAutoJoinNetworkLength,
 			"(ESC to back)",
 		) + "\n")
+	case "tags":
+		s.WriteString(fmt.Sprintf(
+			"Set the tags (comma separated):\n\n%s\n\n(%d/%d)\n\n%s\n\n",
+			m.tagsInput.View(),
+			len(m.tagsInput.Value()),
+			MaxTagsLength,
+			"(ESC to back)",
+		) + "\n")
 	case "view_planet":
 		s.WriteString(m.renderPlanetFileDetailView() + "\n\n(ESC to back)")
 	case "delete_confirm":
 		s.WriteString(m.renderDeleteConfirm() + "\n\n(ESC to back)")
+	case "planet_update":
+		s.WriteString(m.renderPlanetUpdateConfirm() + "\n\n(ESC to skip)")
+	case "profile_changed":
+		s.WriteString(m.renderProfileChangedConfirm() + "\n\n(ESC to keep yours)")
 	case "activate":
 		s.WriteString("\n" + pad)
 		s.WriteString(m.renderActivateView() + "\n\n")
@@ -370,12 +785,6 @@ func (m AppViewModel) View() string {
 			s.WriteString("\n\n(ENTER to back)")
 		}
 		s.WriteString("\n\n")
-	case "import_tip":
-		s.WriteString(fmt.Sprintf(
-			"%s\n\n%s\n\n;-)\n\n(ESC to back)",
-			activateTitleStyle.Render("Please replace your config file to:"),
-			lipgloss.NewStyle().Width(m.currentWindowSize.Width).Render(path.Join(configs.GetDefaultConfigPath(), "profile.json")),
-		))
 	}
 
 	if m.errorMessage != "" {
@@ -384,10 +793,46 @@ func (m AppViewModel) View() string {
 	if m.successMessage != "" {
 		s.WriteString("\n" + filePickerSuccessStyle.Render(m.successMessage))
 	}
+	if m.warningMessage != "" {
+		s.WriteString("\n" + warningStyle.Render(m.warningMessage))
+	}
 
 	return s.String()
 }
 
+// readClipboard 读取剪贴板，OSC52查询需要暂时释放终端
+func readClipboard(p *tea.Program) tea.Cmd {
+	return func() tea.Msg {
+		if text, err := clipboard.ReadAll(); err == nil && text != "" {
+			return clipboardMsg{text: text}
+		}
+		if p != nil {
+			if err := p.ReleaseTerminal(); err == nil {
+				defer p.RestoreTerminal()
+			}
+		}
+		text, err := tools.ReadClipboard()
+		return clipboardMsg{text: text, err: err}
+	}
+}
+
+// fetchCatalogs 在后台拉取目录，由 Update 合并到配置
+func fetchCatalogs(catalogs []configs.ZerotierCatalog) tea.Cmd {
+	catalogs = append([]configs.ZerotierCatalog{}, catalogs...)
+	return func() tea.Msg {
+		msg := catalogSyncMsg{snapshots: map[string]*tools.CatalogSnapshot{}}
+		for _, item := range catalogs {
+			snapshot, err := tools.FetchCatalog(item)
+			if err != nil {
+				msg.errors = append(msg.errors, fmt.Sprintf("%s: %s", item.Name, err.Error()))
+				continue
+			}
+			msg.snapshots[item.Name] = snapshot
+		}
+		return msg
+	}
+}
+
 func (m AppViewModel) parsePlanetFile() (*tools.World, error) {
 	data, err := os.ReadFile(m.filePickerSelected)
 	if err != nil {
@@ -399,28 +844,153 @@ func (m AppViewModel) parsePlanetFile() (*tools.World, error) {
 	}
 	return world, nil
 }
-func (m AppViewModel) makePlanetFileItem(world *tools.World, fileName string) *configs.ZerotierPlanetFile {
-	var root tools.Root
-	var ep tools.InetAddress
-	if len(world.Roots) > 0 {
-		root = world.Roots[0]
-		if len(root.StableEndpoints) > 0 {
-			ep = root.StableEndpoints[0]
+
+// renderEarthStatus 显示是否为官方Earth
+func renderEarthStatus(world *tools.World) string {
+	status := tools.EarthStatus(world)
+	if status == "" {
+		return ""
+	}
+	line := "World: " + tools.EarthLabel(status)
+	if status == tools.EarthImpostor {
+		line = warningStyle.Render(line)
+	}
+	return "  " + line + "\n"
+}
+
+// openResetToDefault 切换回官方Earth：保存到列表中并进入激活确认页面
+func (m *AppViewModel) openResetToDefault() {
+	earth, _, err := tools.EarthWorld(m.config)
+	if err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	planet, _, err := tools.StoreWorld(m.config, earth, "Earth")
+	if err == nil {
+		if err := m.config.WriteAppConfig(); err != nil {
+			m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+			return
+		}
+	}
+	// an existing entry (err != nil) is activated as is
+	items := RenderPlanetListItem(m.config)
+	m.planetList.SetItems(items)
+	for _, item := range items {
+		if p, ok := item.(PlanetItem); ok && p.Planet == planet {
+			m.planetFile = p.Planet
+			m.currentPlanetItem = p
+			m.actionList.Title = m.getActionPageTitle()
+			m.actionList.SetItems(RenderActionListItem(m.currentPlanetItem, len(m.config.Planets) > 1))
+			m.loadInstalledPlanet()
+			m.screen = "activate"
+			return
 		}
 	}
-	if fileName == "" {
-		fileName = ep.String()
+}
+
+// openMergePlan 读取备份并计算合并计划
+func (m *AppViewModel) openMergePlan(backupPath string) {
+	backup, err := configs.ReadProfileFile(backupPath, m.config.PlanetStore())
+	if err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	m.mergePlan = tools.PlanMerge(m.config, backup)
+	if m.mergePlan.IsEmpty() {
+		m.successMessage = "Nothing to merge, " + m.mergePlan.Summary()
+		m.mergePlan = nil
+		return
+	}
+	m.mergeList.SetItems(RenderMergeListItem(m.mergePlan))
+	m.screen = "import_merge"
+}
+
+// applyMergePlan 按选择的处理方式合并备份
+func (m *AppViewModel) applyMergePlan() {
+	if m.mergePlan == nil {
+		return
+	}
+	m.mergePlan.Apply(m.config)
+	if err := m.config.WriteAppConfig(); err != nil {
+		m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+		return
+	}
+	m.successMessage = "Merged: " + m.mergePlan.Summary()
+	m.mergePlan = nil
+	m.planetList.SetItems(RenderPlanetListItem(m.config))
+	m.screen = "list"
+}
+
+func (m AppViewModel) renderMergePlanView() string {
+	var sb strings.Builder
+	sb.WriteString(activateTitleStyle.Render("Merge backup") + "\n\n")
+	sb.WriteString(m.mergePlan.Summary() + "\n")
+	for _, skipped := range m.mergePlan.Skipped {
+		sb.WriteString(warningStyle.Render("skipped "+skipped) + "\n")
+	}
+	if len(m.mergePlan.Conflicts) > 0 {
+		sb.WriteString("\n" + m.mergeList.View())
+	}
+	sb.WriteString("\n\n(ctrl+s to merge, ESC to back)")
+	return sb.String()
+}
+
+// addPlanetFromText 解析粘贴的planet并添加到列表
+func (m *AppViewModel) addPlanetFromText(text string) {
+	world, format, err := tools.DecodeWorldText(text)
+	if err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	planet, isRevision, err := tools.StoreWorld(m.config, world, "")
+	if err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	if err := m.config.WriteAppConfig(); err != nil {
+		m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+		return
 	}
-	return &configs.ZerotierPlanetFile{
-		Hash:         hex.EncodeToString(world.Signature[:32]),
-		Remark:       fileName,
-		Data:         world.ToBase64(),
-		CreateTime:   world.Timestamp,
-		WorldId:      world.ID,
-		WorldType:    world.Type,
-		RootIdentity: root.Identity.String(),
-		RootEndpoint: ep.String(),
+	if isRevision {
+		m.successMessage = fmt.Sprintf("Added (%s) as a revision of %s", format, planet.Remark)
+	} else {
+		m.successMessage = fmt.Sprintf("Added (%s) as %s", format, planet.Remark)
 	}
+	if issues := tools.LintWorld(world, false); len(issues) > 0 {
+		m.warningMessage = RenderLintIssues(issues)
+	}
+	m.planetList.SetItems(RenderPlanetListItem(m.config))
+	m.screen = "list"
+}
+
+// exportPlanet 导出当前planet到当前目录，不覆盖已有的文件
+func (m AppViewModel) exportPlanet(format string) (string, error) {
+	world, err := tools.ParsePlanetBase64(m.planetFile.ActiveRevision().Data)
+	if err != nil {
+		return "", err
+	}
+	data, err := tools.ExportWorld(world, format)
+	if err != nil {
+		return "", err
+	}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	fileName := path.Join(currentDir, tools.ExportFileName(world, format))
+	if _, err := os.Stat(fileName); err == nil {
+		return "", fmt.Errorf("%s exists", fileName)
+	}
+	return fileName, os.WriteFile(fileName, data, 0644)
+}
+
+// openHexView 打开planet的十六进制视图，ESC返回到back
+func (m *AppViewModel) openHexView(world *tools.World, title string, back string) {
+	m.hexViewTitle = fmt.Sprintf("%s (%d bytes)", title, len(world.RawData))
+	m.hexViewBack = back
+	m.hexView.SetContent(RenderHexLayout(world))
+	m.hexView.GotoTop()
+	m.screen = "hex_view"
 }
 
 func (m AppViewModel) savePlanetChange() error {
@@ -443,15 +1013,101 @@ func (m AppViewModel) removePlanet() error {
 	m.config.Planets = pList
 	return m.config.WriteAppConfig()
 }
-func (m AppViewModel) isPlanetFileExists(p *configs.ZerotierPlanetFile) bool {
-	for i := 0; i < len(m.config.Planets); i++ {
-		item := m.config.Planets[i]
-		if item.Hash == p.Hash {
-			return true
+
+// refreshPlanetItems 重建列表，并刷新当前选中的planet
+func (m *AppViewModel) refreshPlanetItems() {
+	m.planetList.SetItems(RenderPlanetListItem(m.config))
+	if m.planetFile == nil {
+		return
+	}
+	for _, item := range m.planetList.Items() {
+		if p, ok := item.(PlanetItem); ok && p.Planet == m.planetFile {
+			m.currentPlanetItem = p
+		}
+	}
+	m.actionList.SetItems(RenderActionListItem(m.currentPlanetItem, len(m.config.Planets) > 1))
+	if m.screen == "revisions" {
+		m.revisionList.SetItems(RenderRevisionListItem(*m.planetFile, tools.GetInstalledRevision(*m.planetFile, tools.GetCurrentPlanetHashFromOS(m.config))))
+	}
+}
+
+// applySetting 校验并立即保存配置项，失败时保留原来的配置
+func (m *AppViewModel) applySetting(setting *configs.ProfileSetting, value string) bool {
+	if source := m.config.EffectiveSetting(setting.Key); source.Source == configs.SourceEnv {
+		m.errorMessage = fmt.Sprintf("%s is set by %s, unset it to change the setting here", setting.Name, source.Origin)
+		return false
+	}
+	previous := *m.config
+	if err := setting.Apply(m.config, value); err != nil {
+		*m.config = previous
+		m.errorMessage = err.Error()
+		return false
+	}
+	if err := m.config.WriteAppConfig(); err != nil {
+		*m.config = previous
+		m.errorMessage = fmt.Sprintf("Save profile error: %s", err.Error())
+		return false
+	}
+	ApplyTheme(m.config.ThemeName())
+	m.installedHash = tools.GetCurrentPlanetHashFromOS(m.config)
+	m.planetList.SetItems(RenderPlanetListItem(m.config))
+	m.settingsList.SetItems(RenderSettingsListItem(m.config))
+	m.successMessage = fmt.Sprintf("%s set to %s", setting.Name, m.config.SettingValue(setting.Key))
+	return true
+}
+
+// watchFiles 定时检查配置文件和当前planet文件
+func watchFiles() tea.Cmd {
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		return watchMsg{}
+	})
+}
+
+// checkFilesChanged 配置文件或当前planet文件被其他程序修改时刷新列表
+func (m *AppViewModel) checkFilesChanged() {
+	if m.activateLock {
+		return
+	}
+	if m.config.ChangedOnDisk() {
+		// other screens may hold entries of the old profile, ask on the next key instead
+		if m.screen != "list" {
+			return
 		}
+		if err := m.config.Reload(); err != nil {
+			m.errorMessage = fmt.Sprintf("Reload profile error: %s", err.Error())
+			return
+		}
+		m.installedHash = tools.GetCurrentPlanetHashFromOS(m.config)
+		m.planetList.SetItems(RenderPlanetListItem(m.config))
+		m.warningMessage = "The profile was changed by another program and has been reloaded"
+		return
+	}
+	installedHash := tools.GetCurrentPlanetHashFromOS(m.config)
+	if installedHash == m.installedHash {
+		return
 	}
-	return false
+	m.installedHash = installedHash
+	m.refreshPlanetItems()
+	if m.screen == "activate" || m.screen == "diff_installed" {
+		m.loadInstalledPlanet()
+	}
+	if m.screen != "list" {
+		return
+	}
+	if update, err := tools.DetectPlanetUpdate(m.config); err == nil && update != nil {
+		m.planetUpdate = update
+		m.confirmCursor = 0
+		m.screen = "planet_update"
+		return
+	}
+	m.warningMessage = "The installed planet file was changed"
 }
+
+// loadInstalledPlanet 读取当前安装的planet，在打开激活或比较页面以及planet文件变化时调用，避免每次渲染都读取文件
+func (m *AppViewModel) loadInstalledPlanet() {
+	m.installedPlanet, m.installedPlanetErr = tools.ParsePlanetFile(configs.GetPlanetFilePath(m.config))
+}
+
 func (m AppViewModel) getActionPageTitle() string {
 	rTitle := m.planetFile.Remark
 	if len(rTitle) > 16 {
@@ -471,15 +1127,36 @@ func (m AppViewModel) renderPlanetFileDetailView() string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintln("ZeroTier Planet Information:"))
 	sb.WriteString(fmt.Sprintf("  ID: %d\n", world.ID))
+	sb.WriteString(renderEarthStatus(world))
 	sb.WriteString(fmt.Sprintf("  Type: %d (1=Planet, 127=Moon)\n", world.Type))
 	sb.WriteString(fmt.Sprintf("  Timestamp: %d\n", world.Timestamp))
 	sb.WriteString(fmt.Sprintf("  Update Signer Public Key: %s\n", hex.EncodeToString(world.UpdatesMustBeSignedBy[:])))
+	if key := tools.FindSigningKeyOfWorld(m.config, world); key != nil {
+		sb.WriteString(fmt.Sprintf("  Signing Key: %s (stored, can sign updates)\n", key.Name))
+	}
 	sb.WriteString(fmt.Sprintf("  Signature: %s...\n", hex.EncodeToString(world.Signature[:16])))
 	sb.WriteString(fmt.Sprintf("  Number of Roots: %d\n", len(world.Roots)))
+	if len(m.planetFile.Revisions) > 0 {
+		sb.WriteString(fmt.Sprintf("  Revisions: %d\n", len(m.planetFile.Revisions)+1))
+		if rev := m.planetFile.FindRevision(m.planetFile.PinnedRevision); rev != nil {
+			sb.WriteString(fmt.Sprintf("  Pinned revision: %d\n", rev.CreateTime))
+		}
+	}
+	if m.planetFile.Catalog != "" {
+		sb.WriteString(fmt.Sprintf("  Catalog: %s (read-only)\n", m.planetFile.Catalog))
+		if len(m.planetFile.Networks) > 0 {
+			sb.WriteString(fmt.Sprintf("  Networks: %s\n", strings.Join(m.planetFile.Networks, ", ")))
+		}
+	}
 
 	for i, root := range world.Roots {
 		sb.WriteString(fmt.Sprintf("\nRoot Server %d:\n", i+1))
-		sb.WriteString(fmt.Sprintf("  Identity: %s\n", root.Identity.String()))
+		sb.WriteString(fmt.Sprintf("  Identity (%s): %s\n", root.Identity.TypeName(), root.Identity.String()))
+		if err := root.Identity.Validate(); errors.Is(err, tools.ErrIdentityValidationUnsupported) {
+			sb.WriteString("  " + warningStyle.Render("Unverified identity: "+err.Error()) + "\n")
+		} else if err != nil {
+			sb.WriteString("  " + filePickerErrorStyle.Render("Invalid identity: "+err.Error()) + "\n")
+		}
 		for j, ep := range root.StableEndpoints {
 			sb.WriteString(fmt.Sprintf("  Endpoint %d: %s\n", j+1, ep.String()))
 		}
@@ -490,26 +1167,49 @@ func (m AppViewModel) renderActivateView() string {
 	if m.planetFile == nil {
 		return ""
 	}
-	world, err := tools.ParsePlanetBase64(m.planetFile.Data)
+	revision := m.planetFile.ActiveRevision()
+	world, err := tools.ParsePlanetBase64(revision.Data)
 	if err != nil {
 		return err.Error()
 	}
+	isMoon := world.Type == tools.ZT_WORLD_TYPE_MOON
 	var sb strings.Builder
-	sb.WriteString(activateTitleStyle.Render("Activate planet file") + "\n\n")
+	if isMoon {
+		sb.WriteString(activateTitleStyle.Render("Install moon file") + "\n\n")
+	} else {
+		sb.WriteString(activateTitleStyle.Render("Activate planet file") + "\n\n")
+	}
+	if revision.Hash != m.planetFile.Hash {
+		sb.WriteString(fmt.Sprintf("Pinned revision: %d (latest is %d)\n\n", revision.CreateTime, m.planetFile.CreateTime))
+	}
 
 	sb.WriteString(fmt.Sprintln("ZeroTier Planet Information:"))
 	sb.WriteString(fmt.Sprintf("  ID: %d\n", world.ID))
+	sb.WriteString(renderEarthStatus(world))
 	sb.WriteString(fmt.Sprintf("  Type: %d (1=Planet, 127=Moon)\n", world.Type))
 
 	for i, root := range world.Roots {
 		sb.WriteString(fmt.Sprintf("\nRoot Server %d:\n", i+1))
-		sb.WriteString(fmt.Sprintf("  Identity: %s\n", root.Identity.String()))
+		sb.WriteString(fmt.Sprintf("  Identity (%s): %s\n", root.Identity.TypeName(), root.Identity.String()))
 		for j, ep := range root.StableEndpoints {
 			sb.WriteString(fmt.Sprintf("  Endpoint %d: %s\n", j+1, ep.String()))
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("\nJoin network: %s\n", m.planetFile.AutoJoinNetwork))
+	if !isMoon {
+		sb.WriteString(fmt.Sprintf("\nJoin network: %s\n", m.planetFile.AutoJoinNetwork))
+	} else {
+		sb.WriteString(fmt.Sprintf("\nWill be installed as moons.d/%s\n", tools.MoonFileName(world)))
+	}
+
+	if issues := tools.LintWorld(world, !isMoon); len(issues) > 0 {
+		sb.WriteString("\n" + warningStyle.Render(RenderLintIssues(issues)) + "\n")
+	}
+
+	if m.installedPlanetErr == nil && !isMoon {
+		sb.WriteString("\nChanges against the installed planet:\n")
+		sb.WriteString(RenderWorldDiff(m.installedPlanet, world))
+	}
 
 	if !m.IsRunAsRoot {
 		sb.WriteString("\n" + filePickerErrorStyle.Render("You must run this program as root (administrator)"))
@@ -518,9 +1218,96 @@ func (m AppViewModel) renderActivateView() string {
 	return sb.String()
 }
 
+func (m AppViewModel) renderRevisionDiffView() string {
+	rItem, ok := m.revisionList.SelectedItem().(RevisionItem)
+	if !ok {
+		return ""
+	}
+	selected, err := tools.ParsePlanetBase64(rItem.Revision.Data)
+	if err != nil {
+		return err.Error()
+	}
+	latest, err := tools.ParsePlanetBase64(m.planetFile.Data)
+	if err != nil {
+		return err.Error()
+	}
+	var sb strings.Builder
+	sb.WriteString(activateTitleStyle.Render(fmt.Sprintf("Revision %d -> latest %d", selected.Timestamp, latest.Timestamp)) + "\n\n")
+	sb.WriteString(RenderWorldDiff(selected, latest))
+	return sb.String()
+}
+
+func (m AppViewModel) renderInstalledDiffView() string {
+	if m.planetFile == nil {
+		return ""
+	}
+	world, err := tools.ParsePlanetBase64(m.planetFile.ActiveRevision().Data)
+	if err != nil {
+		return err.Error()
+	}
+	if m.installedPlanetErr != nil {
+		return m.installedPlanetErr.Error()
+	}
+	var sb strings.Builder
+	sb.WriteString(activateTitleStyle.Render("Installed planet -> "+m.planetFile.Remark) + "\n\n")
+	sb.WriteString(RenderWorldDiff(m.installedPlanet, world))
+	return sb.String()
+}
+
 func (m AppViewModel) renderDeleteConfirm() string {
+	return m.renderConfirm("Do you want to delete the planet file?")
+}
+
+func (m AppViewModel) renderPlanetUpdateConfirm() string {
+	if m.planetUpdate == nil {
+		return ""
+	}
+	planet := m.config.Planets[m.planetUpdate.Index]
+	var sb strings.Builder
+	sb.WriteString(activateTitleStyle.Render("Planet update detected") + "\n\n")
+	sb.WriteString(fmt.Sprintf("The roots have pushed a newer revision of \"%s\".\n", planet.Remark))
+	sb.WriteString(fmt.Sprintf("  ID: %d\n", m.planetUpdate.World.ID))
+	sb.WriteString(fmt.Sprintf("  Timestamp: %d -> %d\n", m.planetUpdate.Previous.Timestamp, m.planetUpdate.World.Timestamp))
+	sb.WriteString("  Signature: valid\n\n")
+	sb.WriteString(m.renderConfirm("Do you want to update the stored planet file? (older revisions are kept)"))
+	return sb.String()
+}
+
+func (m AppViewModel) renderProfileChangedConfirm() string {
+	var sb strings.Builder
+	sb.WriteString(activateTitleStyle.Render("Profile changed") + "\n\n")
+	sb.WriteString(fmt.Sprintf("%s was changed by another program.\n", m.config.ConfigPath()))
+	sb.WriteString("Keeping yours overwrites those changes the next time the profile is saved.\n\n")
+	sb.WriteString(m.renderConfirm("Do you want to reload the profile?"))
+	return sb.String()
+}
+
+// reloadProfile 重新读取被其他程序修改的配置，回到列表
+func (m *AppViewModel) reloadProfile() {
+	if err := m.config.Reload(); err != nil {
+		m.errorMessage = fmt.Sprintf("Reload profile error: %s", err.Error())
+		m.screen = "list"
+		return
+	}
+	m.planetFile = nil
+	m.editWorld = nil
+	m.mergePlan = nil
+	m.planetList.SetItems(RenderPlanetListItem(m.config))
+	m.screen = "list"
+	m.successMessage = "Profile reloaded"
+}
+
+// keepLocalProfile 保留内存中的配置，下次保存时覆盖其他程序的修改
+func (m *AppViewModel) keepLocalProfile() {
+	if err := m.config.IgnoreExternalChanges(); err != nil {
+		m.errorMessage = err.Error()
+	}
+	m.screen = m.profileChangedBack
+}
+
+func (m AppViewModel) renderConfirm(question string) string {
 	s := strings.Builder{}
-	s.WriteString("Do you want to delete the planet file?\n\n")
+	s.WriteString(question + "\n\n")
 	choices := []string{"Yes", "No"}
 	for i := 0; i < len(choices); i++ {
 		if m.confirmCursor == i {
@@ -536,16 +1323,234 @@ func (m AppViewModel) renderDeleteConfirm() string {
 
 func CreateAppView(cfg *configs.ZerotierSwitcherProfile) (*AppViewModel, error) {
 	m := AppViewModel{
-		IsRunAsRoot:    tools.IsRunAsRoot(),
-		screen:         "list",
-		config:         cfg,
-		planetList:     CreatePlanetListView(cfg),
-		actionList:     CreateActionListView(),
-		filePickerView: filepicker.New(),
-		remarkInput:    CreateRemarkInput("remark text", MaxRemarkLength),
-		autoJoinInput:  CreateRemarkInput("network id", MaxAutoJoinNetworkLength),
-		progressBar:    progress.New(progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
+		IsRunAsRoot:      tools.IsRunAsRoot(),
+		screen:           "list",
+		config:           cfg,
+		planetList:       CreatePlanetListView(cfg),
+		actionList:       CreateActionListView(),
+		revisionList:     CreateRevisionListView(),
+		exportList:       CreateExportListView(),
+		hexView:          CreateHexView(),
+		editList:         CreateEditorListView(),
+		editInput:        CreateRemarkInput("", MaxEditInputLength),
+		addTextInput:     CreateTextArea("base64, hex or {0x01,0x00,...}"),
+		filePickerView:   filepicker.New(),
+		importPickerView: filepicker.New(),
+		mergeList:        CreateMergeListView(),
+		backupList:       CreateBackupListView(),
+		settingsList:     CreateSettingsListView(),
+		settingInput:     CreateRemarkInput("", MaxEditInputLength),
+		remarkInput:      CreateRemarkInput("remark text", MaxRemarkLength),
+		autoJoinInput:    CreateRemarkInput("network id", MaxAutoJoinNetworkLength),
+		tagsInput:        CreateRemarkInput("tags", MaxTagsLength),
+		progressBar:      progress.New(progress.WithScaledGradient("#FF7CCB", "#FDFF8C")),
+		installedHash:    tools.GetCurrentPlanetHashFromOS(cfg),
+	}
+	ApplyTheme(cfg.ThemeName())
+	if errs := cfg.PlanetErrors(); len(errs) > 0 {
+		m.warningMessage = fmt.Sprintf("%d planets could not be loaded (see config validate): %v", len(errs), errs[0])
 	}
 	m.filePickerView.CurrentDirectory, _ = os.UserHomeDir()
+	m.importPickerView.CurrentDirectory, _ = os.Getwd()
+	m.importPickerView.AllowedTypes = []string{".json"}
+	if update, err := tools.DetectPlanetUpdate(cfg); err == nil && update != nil {
+		m.planetUpdate = update
+		m.confirmCursor = 0
+		m.screen = "planet_update"
+	}
 	return &m, nil
 }
+
+// refreshEditorItems 重建编辑器列表并选中指定的行
+func (m *AppViewModel) refreshEditorItems(root, endpoint int) {
+	title := fmt.Sprintf("Edit: %s  (timestamp %d", m.planetFile.Remark, m.editWorld.Timestamp)
+	if m.editModified {
+		title += ", unsaved changes"
+	}
+	m.editList.Title = title + ")"
+	items := RenderEditorListItem(m.editWorld)
+	m.editList.SetItems(items)
+	m.editList.Select(findEditorItem(items, root, endpoint))
+}
+
+// handleEditorKey 处理编辑器的快捷键
+func (m *AppViewModel) handleEditorKey(k string) {
+	item, selected := m.editList.SelectedItem().(EditorItem)
+	switch k {
+	case "a":
+		if len(m.editWorld.Roots) >= tools.ZT_WORLD_MAX_ROOTS {
+			m.errorMessage = fmt.Sprintf("A world can have at most %d roots", tools.ZT_WORLD_MAX_ROOTS)
+			return
+		}
+		m.openEditInput("root")
+	case "e":
+		if !selected {
+			m.errorMessage = "Add a root first"
+			return
+		}
+		m.openEditInput("endpoint")
+	case "x":
+		if !selected {
+			return
+		}
+		if item.Endpoint < 0 {
+			m.editWorld.RemoveRoot(item.Root)
+		} else {
+			m.editWorld.RemoveEndpoint(item.Root, item.Endpoint)
+		}
+		m.editModified = true
+		m.refreshEditorItems(item.Root, item.Endpoint-1)
+	case "[", "]":
+		if !selected || item.Endpoint < 0 {
+			return
+		}
+		delta := 1
+		if k == "[" {
+			delta = -1
+		}
+		index := m.editWorld.MoveEndpoint(item.Root, item.Endpoint, delta)
+		m.editModified = m.editModified || index != item.Endpoint
+		m.refreshEditorItems(item.Root, index)
+	case "t":
+		m.editWorld.BumpTimestamp()
+		m.editModified = true
+		m.refreshEditorItems(item.Root, item.Endpoint)
+	case "ctrl+s":
+		if err := m.editWorld.ValidateEdit(); err != nil {
+			m.errorMessage = err.Error()
+			return
+		}
+		if tools.FindSigningKeyOfWorld(m.config, m.editWorld) != nil {
+			m.openEditInput("passphrase")
+		} else {
+			m.openEditInput("key")
+		}
+	}
+}
+
+func (m *AppViewModel) openEditInput(mode string) {
+	m.editInputMode = mode
+	m.editInput.SetValue("")
+	m.editInput.EchoMode = textinput.EchoNormal
+	switch mode {
+	case "root":
+		m.editInput.Placeholder = "identity.public file or identity string"
+	case "endpoint":
+		m.editInput.Placeholder = "ip/port"
+	case "key":
+		m.editInput.Placeholder = "current.c25519 file"
+	case "passphrase":
+		m.editInput.Placeholder = "passphrase"
+		m.editInput.EchoMode = textinput.EchoPassword
+	}
+	m.editInput.Width = m.currentWindowSize.Width - 4
+	m.screen = "edit_input"
+}
+
+func (m AppViewModel) getEditInputPrompt() string {
+	switch m.editInputMode {
+	case "root":
+		return fmt.Sprintf("Add a root (%d/%d), enter an identity file path or an identity string:", len(m.editWorld.Roots)+1, tools.ZT_WORLD_MAX_ROOTS)
+	case "endpoint":
+		item, _ := m.editList.SelectedItem().(EditorItem)
+		return fmt.Sprintf("Add a stable endpoint to root %s (e.g. 203.0.113.1/9993):", m.editWorld.Roots[item.Root].Identity.AddressString())
+	case "passphrase":
+		key := tools.FindSigningKeyOfWorld(m.config, m.editWorld)
+		return fmt.Sprintf("Sign with the stored key %s, enter its passphrase:", key.Name)
+	default:
+		return fmt.Sprintf("Sign with the update signing key %s... (path of the key pair file):", hex.EncodeToString(m.editWorld.UpdatesMustBeSignedBy[32:40]))
+	}
+}
+
+// applyEditInput 应用编辑器输入框的内容
+func (m *AppViewModel) applyEditInput() {
+	value := m.editInput.Value()
+	if m.editInputMode != "passphrase" {
+		value = strings.TrimSpace(value)
+	}
+	if value == "" {
+		return
+	}
+	item, _ := m.editList.SelectedItem().(EditorItem)
+	switch m.editInputMode {
+	case "root":
+		var identity *tools.Identity
+		var err error
+		if s, sErr := os.Stat(value); sErr == nil && !s.IsDir() {
+			identity, _, err = tools.ReadIdentityFile(value)
+		} else {
+			identity, _, err = tools.ParseIdentityString(value)
+		}
+		if err == nil {
+			err = m.editWorld.AddRoot(*identity)
+		}
+		if err != nil {
+			m.errorMessage = err.Error()
+			return
+		}
+		m.editModified = true
+		m.refreshEditorItems(len(m.editWorld.Roots)-1, -1)
+		m.successMessage = "Root added, add its stable endpoints with 'e'"
+	case "endpoint":
+		ep, err := tools.ParseEndpoint(value)
+		if err == nil {
+			err = m.editWorld.AddEndpoint(item.Root, ep)
+		}
+		if err != nil {
+			m.errorMessage = err.Error()
+			return
+		}
+		m.editModified = true
+		m.refreshEditorItems(item.Root, len(m.editWorld.Roots[item.Root].StableEndpoints)-1)
+	case "key", "passphrase":
+		var key *tools.C25519KeyPair
+		var err error
+		if m.editInputMode == "key" {
+			key, err = tools.ReadC25519KeyPair(value)
+		} else {
+			key, err = tools.DecryptSigningKey(tools.FindSigningKeyOfWorld(m.config, m.editWorld), value)
+			m.editInput.SetValue("")
+		}
+		if err != nil {
+			m.errorMessage = err.Error()
+			return
+		}
+		if err = m.saveEditedPlanet(key); err != nil {
+			m.errorMessage = err.Error()
+			return
+		}
+		m.editWorld = nil
+		m.actionList.Title = m.getActionPageTitle()
+		m.screen = "action"
+		return
+	}
+	m.screen = "edit_planet"
+}
+
+// saveEditedPlanet 签名编辑后的planet并保存为新版本
+func (m *AppViewModel) saveEditedPlanet(key *tools.C25519KeyPair) error {
+	world := m.editWorld
+	if world.Timestamp <= m.planetFile.CreateTime {
+		// nodes only accept updates with a newer timestamp
+		world.BumpTimestamp()
+	}
+	if err := world.Sign(key); err != nil {
+		return err
+	}
+	signed, err := tools.ParseWorld(world.RawData)
+	if err != nil {
+		return fmt.Errorf("signed planet does not parse: %v", err)
+	}
+	if !m.planetFile.AddRevision(tools.MakePlanetRevision(signed)) {
+		return fmt.Errorf("revision already exists")
+	}
+	if err := m.config.WriteAppConfig(); err != nil {
+		return fmt.Errorf("Save profile error: %s", err.Error())
+	}
+	m.refreshPlanetItems()
+	m.successMessage = fmt.Sprintf("Saved as revision %d", signed.Timestamp)
+	if issues := tools.LintWorld(signed, false); len(issues) > 0 {
+		m.warningMessage = RenderLintIssues(issues)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package views
 
 import (
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
@@ -12,3 +13,13 @@ func CreateRemarkInput(placeholder string, textLimit int) textinput.Model {
 	ti.Width = 32
 	return ti
 }
+
+func CreateTextArea(placeholder string) textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = placeholder
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.Focus()
+	return ta
+}
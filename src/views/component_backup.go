@@ -0,0 +1,37 @@
+package views
+
+import (
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"time"
+)
+
+type BackupItem struct {
+	Backup configs.ProfileBackup
+}
+
+func (i BackupItem) FilterValue() string { return "" }
+func (i BackupItem) Title() string       { return i.Backup.Time.Format(time.DateTime) }
+func (i BackupItem) Description() string { return i.Backup.Describe() }
+
+var backupRestoreKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "restore"))
+
+func CreateBackupListView() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 30)
+	l.Title = "Backups"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{backupRestoreKey}
+	}
+	return l
+}
+
+func RenderBackupListItem(backups []configs.ProfileBackup) []list.Item {
+	items := make([]list.Item, len(backups))
+	for i, backup := range backups {
+		items[i] = BackupItem{Backup: backup}
+	}
+	return items
+}
@@ -0,0 +1,69 @@
+package views
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// EditorItem 编辑器中的一行，Endpoint为-1时表示根服务器本身
+type EditorItem struct {
+	Root     int
+	Endpoint int
+	Name     string
+	Desc     string
+}
+
+func (i EditorItem) FilterValue() string { return "" }
+func (i EditorItem) Title() string       { return i.Name }
+func (i EditorItem) Description() string { return i.Desc }
+
+var editorAddRootKey = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add root"))
+var editorAddEndpointKey = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "add endpoint"))
+var editorRemoveKey = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "remove"))
+var editorMoveUpKey = key.NewBinding(key.WithKeys("["), key.WithHelp("[/]", "move endpoint"))
+var editorMoveDownKey = key.NewBinding(key.WithKeys("]"))
+var editorTimestampKey = key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "bump timestamp"))
+var editorSaveKey = key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "sign & save"))
+
+func CreateEditorListView() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 30)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{editorAddRootKey, editorAddEndpointKey, editorRemoveKey, editorMoveUpKey, editorTimestampKey, editorSaveKey}
+	}
+	return l
+}
+
+func RenderEditorListItem(world *tools.World) []list.Item {
+	items := make([]list.Item, 0)
+	for i, root := range world.Roots {
+		items = append(items, EditorItem{
+			Root:     i,
+			Endpoint: -1,
+			Name:     fmt.Sprintf("Root %d: %s (%s)", i+1, root.Identity.AddressString(), root.Identity.TypeName()),
+			Desc:     fmt.Sprintf("%d stable endpoint(s)", len(root.StableEndpoints)),
+		})
+		for j, ep := range root.StableEndpoints {
+			items = append(items, EditorItem{
+				Root:     i,
+				Endpoint: j,
+				Name:     fmt.Sprintf("  ↳ %s", ep.String()),
+				Desc:     fmt.Sprintf("  endpoint %d of root %d", j+1, i+1),
+			})
+		}
+	}
+	return items
+}
+
+// findEditorItem 查找编辑器中对应的行，用于修改后保持选中位置
+func findEditorItem(items []list.Item, root, endpoint int) int {
+	for i, item := range items {
+		if e, ok := item.(EditorItem); ok && e.Root == root && e.Endpoint == endpoint {
+			return i
+		}
+	}
+	return 0
+}
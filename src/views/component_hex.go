@@ -0,0 +1,42 @@
+package views
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+	"strings"
+)
+
+var hexFieldStyles = []lipgloss.Style{
+	lipgloss.NewStyle().Foreground(lipgloss.Color("12")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("13")),
+}
+var hexOffsetStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+var hexProblemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+func CreateHexView() viewport.Model {
+	return viewport.New(80, 20)
+}
+
+// RenderHexLayout 渲染planet的十六进制视图，相邻字段使用不同颜色区分
+func RenderHexLayout(w *tools.World) string {
+	var sb strings.Builder
+	for _, row := range tools.HexLayout(w) {
+		style := hexFieldStyles[row.Index%len(hexFieldStyles)]
+		label := row.Field
+		if row.Value != "" {
+			label += " = " + row.Value
+		}
+		sb.WriteString(hexOffsetStyle.Render(fmt.Sprintf("%08x", row.Offset)))
+		sb.WriteString("  ")
+		sb.WriteString(style.Render(fmt.Sprintf("%-*s", tools.HexBytesPerRow*3-1, row.HexString())))
+		sb.WriteString("  ")
+		sb.WriteString(style.Render(label))
+		if row.Problem != "" {
+			sb.WriteString("  " + hexProblemStyle.Render("<- "+row.Problem))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -1,17 +1,19 @@
 package views
 
 import (
+	"fmt"
 	"github.com/LanceLRQ/zerotier-switcher/src/configs"
 	"github.com/LanceLRQ/zerotier-switcher/src/tools"
 	"github.com/charmbracelet/bubbles/list"
 )
 
 type PlanetItem struct {
-	Planet    *configs.ZerotierPlanetFile
-	Id        string
-	Name      string
-	Desc      string
-	IsCurrent bool
+	Planet      *configs.ZerotierPlanetFile
+	Id          string
+	Name        string
+	Desc        string
+	IsCurrent   bool // the revision used for activation is installed
+	IsInstalled bool // any revision of the planet is installed
 }
 
 func (i PlanetItem) FilterValue() string { return i.Name }
@@ -29,34 +31,72 @@ func (i ActionItem) Title() string       { return i.Name }
 func (i ActionItem) Description() string { return i.Desc }
 
 func CreatePlanetListView(cfg *configs.ZerotierSwitcherProfile) list.Model {
-	l := list.New(RenderPlanetListItem(cfg.Planets), list.NewDefaultDelegate(), 0, 0)
+	l := list.New(RenderPlanetListItem(cfg), list.NewDefaultDelegate(), 0, 0)
 	l.SetShowStatusBar(false)
 	l.Title = "Planet List"
 	return l
 }
 
-func RenderPlanetListItem(planets []configs.ZerotierPlanetFile) []list.Item {
-	cHash := tools.GetCurrentPlanetHashFromOS()
+func RenderPlanetListItem(cfg *configs.ZerotierSwitcherProfile) []list.Item {
+	planets := cfg.Planets
+	cHash := tools.GetCurrentPlanetHashFromOS(cfg)
 	planetListItems := make([]list.Item, len(planets))
 	for i := range planets {
-		isCurrent := tools.CheckIsCurrentPlanet(planets[i].Data, cHash)
+		installed := tools.GetInstalledRevision(planets[i], cHash)
+		isCurrent := installed != "" && installed == planets[i].ActiveRevision().Hash
 		name := planets[i].Remark
-		if isCurrent {
+		if installed == planets[i].Hash {
 			name += " (current)"
+		} else if installed != "" {
+			name += " (current, older revision)"
+		}
+		if planets[i].WorldId == tools.ZT_WORLD_ID_EARTH {
+			if world, err := tools.ParsePlanetBase64(planets[i].Data); err == nil {
+				switch tools.EarthStatus(world) {
+				case tools.EarthOfficial:
+					name += " (official Earth)"
+				case tools.EarthUnverified:
+					name += " (Earth)"
+				case tools.EarthImpostor:
+					name += " (not the official Earth!)"
+				}
+			}
+		}
+		desc := planets[i].RootEndpoint
+		if planets[i].Catalog != "" {
+			desc += fmt.Sprintf(" [catalog: %s]", planets[i].Catalog)
+		}
+		if planets[i].Retired {
+			desc += " [retired]"
+		}
+		if planets[i].Data == "" {
+			desc += " [data missing]"
 		}
 		planetListItems[i] = PlanetItem{
-			Planet:    &planets[i],
-			Id:        planets[i].Hash,
-			Name:      name,
-			Desc:      planets[i].RootEndpoint,
-			IsCurrent: isCurrent,
+			Planet:      &planets[i],
+			Id:          planets[i].Hash,
+			Name:        name,
+			Desc:        desc,
+			IsCurrent:   isCurrent,
+			IsInstalled: installed != "",
 		}
 	}
 	planetListItems = append(planetListItems, []list.Item{
 		PlanetItem{Id: "add", Name: "+ Add new", Desc: "select a zerotier planet file"},
+		PlanetItem{Id: "add_text", Name: "+ Add from text", Desc: "paste a planet as base64, hex or C array"},
+		PlanetItem{Id: "reset_default", Name: "⌂ Reset to default", Desc: "switch back to the official ZeroTier Earth"},
 		PlanetItem{Id: "backup", Name: "→ Backup", Desc: "Backup config file to current directory"},
-		PlanetItem{Id: "import", Name: "← Import", Desc: "See how to import config file"},
+		PlanetItem{Id: "import", Name: "← Import", Desc: "Merge a backup file into the list"},
+		PlanetItem{Id: "restore", Name: "↺ Restore", Desc: "Restore an automatic backup of the profile"},
+		PlanetItem{Id: "settings", Name: "⚙ Settings", Desc: "ZeroTier paths, activation options, backups and theme"},
 	}...)
+	if len(cfg.Catalogs) > 0 {
+		planetListItems = append(planetListItems, PlanetItem{
+			Id:   "catalog_sync",
+			Name: "⟳ Sync catalogs",
+			Desc: fmt.Sprintf("Sync %d subscribed catalog(s)", len(cfg.Catalogs)),
+		})
+	}
 	return planetListItems
 }
 
@@ -67,17 +107,55 @@ func CreateActionListView() list.Model {
 	return l
 }
 
+func CreateExportListView() list.Model {
+	descs := map[string]string{
+		tools.ExportFormatBinary:  "Raw planet file, as ZeroTier reads it",
+		tools.ExportFormatBase64:  "Base64 text",
+		tools.ExportFormatHex:     "Hex text",
+		tools.ExportFormatCpp:     "C++ array as in ZeroTier's Topology.cpp",
+		tools.ExportFormatJSON:    "JSON description of the decoded world",
+		tools.ExportFormatMkworld: "mkworld JSON config (initmoon definition for moons)",
+	}
+	items := make([]list.Item, len(tools.ExportFormats))
+	for i, format := range tools.ExportFormats {
+		items[i] = ActionItem{Id: format, Name: format, Desc: descs[format]}
+	}
+	l := list.New(items, list.NewDefaultDelegate(), 40, 30)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Title = "Export to the current directory as"
+	return l
+}
+
 func RenderActionListItem(pItem PlanetItem, deleteAble bool) []list.Item {
 	actionList := make([]list.Item, 0)
 	if !pItem.IsCurrent {
 		actionList = append(actionList, ActionItem{Id: "activate", Name: "Activate", Desc: "Activate the planet file"})
 	}
+	actionList = append(actionList, ActionItem{Id: "view", Name: "View info", Desc: "View the info of planet file"})
+	actionList = append(actionList, ActionItem{Id: "hex", Name: "Hex view", Desc: "Show the raw bytes with every field labelled"})
+	actionList = append(actionList, ActionItem{Id: "export", Name: "Export", Desc: "Export as planet file, base64, hex, C++ array or JSON"})
+	if !pItem.IsCurrent {
+		actionList = append(actionList, ActionItem{Id: "diff", Name: "Diff", Desc: "Compare with the installed planet file"})
+	}
+	// 目录管理的planet只读
+	if pItem.Planet != nil && pItem.Planet.IsReadOnly() {
+		return actionList
+	}
 	actionList = append(actionList, []list.Item{
-		ActionItem{Id: "view", Name: "View info", Desc: "View the info of planet file"},
 		ActionItem{Id: "rename", Name: "Rename", Desc: "Rename the planet file"},
 		ActionItem{Id: "auto_join", Name: "Auto join", Desc: "Set auto join network id"},
+		ActionItem{Id: "tags", Name: "Tags", Desc: "Set tags used to publish the planet file"},
+		ActionItem{Id: "edit", Name: "Edit", Desc: "Edit roots and endpoints, re-sign and save as a new revision"},
 	}...)
-	if deleteAble && !pItem.IsCurrent {
+	if pItem.Planet != nil && len(pItem.Planet.Revisions) > 0 {
+		actionList = append(actionList, ActionItem{
+			Id:   "revisions",
+			Name: "Revisions",
+			Desc: fmt.Sprintf("Browse %d revisions, diff and pin one for activation", len(pItem.Planet.Revisions)+1),
+		})
+	}
+	if deleteAble && !pItem.IsInstalled {
 		actionList = append(actionList, ActionItem{Id: "delete", Name: "Delete", Desc: "Delete the planet file"})
 	}
 	return actionList
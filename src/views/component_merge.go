@@ -0,0 +1,51 @@
+package views
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"strings"
+)
+
+type MergeConflictItem struct {
+	Conflict *tools.MergeConflict
+}
+
+func (i MergeConflictItem) FilterValue() string { return "" }
+func (i MergeConflictItem) Title() string {
+	side := "keep local"
+	if i.Conflict.UseBackup {
+		side = "use backup"
+	}
+	return fmt.Sprintf("%s (%s)", i.Conflict.Local.Remark, side)
+}
+func (i MergeConflictItem) Description() string {
+	fields := make([]string, len(i.Conflict.Fields))
+	for j, field := range i.Conflict.Fields {
+		fields[j] = fmt.Sprintf("%s: %q → %q", field.Name, field.Local, field.Backup)
+	}
+	return strings.Join(fields, "; ")
+}
+
+var mergeToggleKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "local/backup"))
+var mergeApplyKey = key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "merge"))
+
+func CreateMergeListView() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 30)
+	l.Title = "Conflicts"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{mergeToggleKey, mergeApplyKey}
+	}
+	return l
+}
+
+func RenderMergeListItem(plan *tools.MergePlan) []list.Item {
+	items := make([]list.Item, len(plan.Conflicts))
+	for i, conflict := range plan.Conflicts {
+		items[i] = MergeConflictItem{Conflict: conflict}
+	}
+	return items
+}
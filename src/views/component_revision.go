@@ -0,0 +1,55 @@
+package views
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"time"
+)
+
+type RevisionItem struct {
+	Revision configs.ZerotierPlanetRevision
+	Name     string
+	Desc     string
+}
+
+func (i RevisionItem) FilterValue() string { return "" }
+func (i RevisionItem) Title() string       { return i.Name }
+func (i RevisionItem) Description() string { return i.Desc }
+
+var revisionPinKey = key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin/unpin"))
+var revisionDiffKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "diff with latest"))
+
+func CreateRevisionListView() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 30)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{revisionDiffKey, revisionPinKey}
+	}
+	return l
+}
+
+func RenderRevisionListItem(planet configs.ZerotierPlanetFile, installed string) []list.Item {
+	revisions := planet.AllRevisions()
+	items := make([]list.Item, len(revisions))
+	for i, rev := range revisions {
+		name := fmt.Sprintf("Revision %d", rev.CreateTime)
+		if rev.Hash == planet.Hash {
+			name += " (latest)"
+		}
+		if rev.Hash == planet.PinnedRevision {
+			name += " (pinned)"
+		}
+		if rev.Hash == installed {
+			name += " (installed)"
+		}
+		desc := fmt.Sprintf("%s  %s", rev.Hash[:16], rev.RootEndpoint)
+		if rev.ReplacedTime > 0 {
+			desc += fmt.Sprintf("  replaced at %s", time.Unix(rev.ReplacedTime, 0).Format(time.DateTime))
+		}
+		items[i] = RevisionItem{Revision: rev, Name: name, Desc: desc}
+	}
+	return items
+}
@@ -0,0 +1,78 @@
+package views
+
+import (
+	"fmt"
+	"github.com/LanceLRQ/zerotier-switcher/src/configs"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+type SettingItem struct {
+	Setting *configs.ProfileSetting
+	Value   string
+	Source  configs.SettingSource
+}
+
+func (i SettingItem) FilterValue() string { return "" }
+func (i SettingItem) Title() string {
+	switch i.Source.Source {
+	case configs.SourceDefault:
+		return fmt.Sprintf("%s: %s (default)", i.Setting.Name, i.Value)
+	case configs.SourceSystem:
+		return fmt.Sprintf("%s: %s (system)", i.Setting.Name, i.Value)
+	case configs.SourceEnv:
+		return fmt.Sprintf("%s: %s (%s)", i.Setting.Name, i.Value, i.Source.Origin)
+	}
+	return fmt.Sprintf("%s: %s", i.Setting.Name, i.Value)
+}
+func (i SettingItem) Description() string { return i.Setting.Desc }
+
+var settingEditKey = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change"))
+var settingResetKey = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "reset to default"))
+
+func CreateSettingsListView() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 30)
+	l.Title = "Settings"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{settingEditKey, settingResetKey}
+	}
+	return l
+}
+
+func RenderSettingsListItem(cfg *configs.ZerotierSwitcherProfile) []list.Item {
+	items := make([]list.Item, len(configs.ProfileSettings))
+	for i, source := range cfg.EffectiveSettings() {
+		items[i] = SettingItem{
+			Setting: source.Setting,
+			Value:   source.Value,
+			Source:  source,
+		}
+	}
+	return items
+}
+
+var terminalColorProfile *termenv.Profile
+var terminalDarkBackground bool
+
+// ApplyTheme 切换界面配色，auto 使用终端检测到的配色
+func ApplyTheme(theme string) {
+	if terminalColorProfile == nil {
+		profile := lipgloss.ColorProfile()
+		terminalColorProfile = &profile
+		terminalDarkBackground = lipgloss.HasDarkBackground()
+	}
+	lipgloss.SetColorProfile(*terminalColorProfile)
+	lipgloss.SetHasDarkBackground(terminalDarkBackground)
+	switch theme {
+	case configs.ThemeDark:
+		lipgloss.SetHasDarkBackground(true)
+	case configs.ThemeLight:
+		lipgloss.SetHasDarkBackground(false)
+	case configs.ThemePlain:
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
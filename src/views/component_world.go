@@ -0,0 +1,41 @@
+package views
+
+import (
+	"github.com/LanceLRQ/zerotier-switcher/src/tools"
+	"github.com/charmbracelet/lipgloss"
+	"strings"
+)
+
+var diffAddedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+var diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+var diffModifiedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+
+// RenderWorldDiff 渲染两个planet之间的差异
+func RenderWorldDiff(from, to *tools.World) string {
+	changes := tools.DiffWorlds(from, to)
+	if len(changes) == 0 {
+		return "No differences.\n"
+	}
+	var sb strings.Builder
+	for _, change := range changes {
+		switch change.Kind {
+		case tools.WorldChangeAdded:
+			sb.WriteString(diffAddedStyle.Render(change.String()))
+		case tools.WorldChangeRemoved:
+			sb.WriteString(diffRemovedStyle.Render(change.String()))
+		default:
+			sb.WriteString(diffModifiedStyle.Render(change.String()))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// RenderLintIssues 渲染planet检查发现的问题
+func RenderLintIssues(issues []tools.LintIssue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}